@@ -1,13 +1,14 @@
 package geecache
 
 import (
-	"geecache/geecache/lru"
+	"geecache/geecache/policy"
 	"sync"
 )
 
 type cache struct {
 	mu         sync.Mutex
-	lru        *lru.Cache
+	policy     policy.Policy
+	newPolicy  policy.Factory // 创建淘汰策略，为 nil 时使用 LRU
 	cacheBytes int64
 }
 
@@ -15,20 +16,24 @@ func (c *cache) add(key string, value ByteView) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.lru == nil {
-		c.lru = lru.New(c.cacheBytes, nil)
+	if c.policy == nil {
+		newPolicy := c.newPolicy
+		if newPolicy == nil {
+			newPolicy = policy.NewLRU
+		}
+		c.policy = newPolicy(c.cacheBytes, nil)
 	}
-	c.lru.Add(key, value)
+	c.policy.Add(key, value)
 }
 
 func (c *cache) get(key string) (value ByteView, ok bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.lru == nil {
+	if c.policy == nil {
 		return
 	}
-	if v, ok := c.lru.Get(key); ok {
+	if v, ok := c.policy.Get(key); ok {
 		return v.(ByteView), ok
 	}
 
@@ -2,75 +2,72 @@ package consistenthash
 
 import (
 	"fmt"
-	"math/rand"
+	"hash/crc32"
+	"sort"
 	"strconv"
-	"testing"
 )
 
-// 测试一致性哈希中哈希环的增加节点、查询节点的功能
-func TestConsistentHash(t *testing.T) {
+/**
+一致性哈希解决什么问题？
+	每个数据，都会准确的分配到一个节点上。访问时，根据该数据的hash值，确定该访问哪个节点。
+	数据倾斜问题：服务器节点过少时，会导致数据无法均匀分配在各节点上。使用虚拟节点解决。
+	删除节点或增加节点时，只需要调整该节点的数据。
+*/
 
-	// 初始化一致性 Map 时，需要传入自定义的哈希函数
-	// 这里为了便于观察，传入的哈希函数，不进行哈希计算，直接返回节点名。
-	hash := New(3, func(key []byte) uint32 {
-		i, _ := strconv.Atoi(string(key))
-		return uint32(i)
-	})
+type Hash func(data []byte) uint32 // hash 函数类型
 
-	hash.Add("6", "4", "2") // 在一致性哈希的哈希环中，加入 节点6、节点4、节点2
+type Map struct { // 一致性哈希算法的主要数据结构
+	hash     Hash           // 设置自定义一种哈希算法函数
+	replicas int            // 虚拟节点的倍数
+	keys     []int          // 哈希环
+	hashMap  map[int]string // 虚拟节点和真实节点的映射表，key 是虚拟节点的哈希值，value 是真实节点的名称
+}
 
-	testCases := map[string]string{
-		"2":  "2",
-		"11": "2",
-		"23": "4",
-		"27": "2",
+func New(replicas int, fn Hash) *Map {
+	m := &Map{
+		hash:     fn,
+		replicas: replicas,
+		hashMap:  make(map[int]string),
 	}
-
-	// 在哈希环中，找到 k 对应的节点，判断节点是否和 正确答案v 一致。
-	for k, v := range testCases {
-		if hash.Get(k) != v {
-			t.Errorf("Asking for %s, should have yielded %s", k, v)
-		}
+	if m.hash == nil { // 默认使用 crc32.ChecksumIEEE 哈希算法
+		m.hash = crc32.ChecksumIEEE
 	}
+	return m
+}
 
-	hash.Add("8") // 在哈希环中增加新节点8
-
-	testCases["27"] = "8" // 因为加入了新节点，经计算，key = 27 将从节点2 迁移到 节点8。
-
-	for k, v := range testCases {
-		if hash.Get(k) != v {
-			t.Errorf("Asking for %s, should have yielded %s", k, v)
+// Add 在哈希环 Map.keys 中加入真实节点
+// 节点名和虚拟节点名经过 hash 计算后得到哈希值，将哈希值加入 Map.keys 中，并排序。
+func (m *Map) Add(keys ...string) {
+	for _, key := range keys { // 对于每个真实节点，添加 m.replicas 个虚拟节点。
+		for i := 0; i < m.replicas; i++ {
+			hash := int(m.hash([]byte(strconv.Itoa(i) + key))) // 计算虚拟节点的 hash 值
+			m.keys = append(m.keys, hash)                      // 虚拟节点加入环
+			m.hashMap[hash] = key                              // 将虚拟节点和真实节点加入映射表
 		}
 	}
+	sort.Ints(m.keys) // 对虚拟节点排序
+	fmt.Println(m.keys)
 }
 
-// 测试虚拟节点个数，对于数据倾斜问题的影响
-func TestConsistentHash2(t *testing.T) {
-	hash := New(6, nil) // 传入 nil，则使用默认的哈希函数 crc32.ChecksumIEEE
-
-	hash.Add("1", "2", "3", "4", "5", "6")
-	m := make(map[int]int)
-
-	num := 0
-
-	for i := 0; i < 10000000; i++ {
-		node := hash.Get(strconv.Itoa(i + rand.Int()))
-		index, _ := strconv.Atoi(node)
-		m[index]++ // 统计每个节点的个数
+// Get 获取 key 所在节点名
+// key 经过 hash 计算后得到哈希值，在哈希环 Map.keys 上查找最接近的节点。
+// 例如：key 的哈希值是 10000，哈希环上找到最接近的两个节点是 8000、11000，应该存在 8000 这个节点上。
+func (m *Map) Get(key string) string {
+	if len(m.keys) == 0 {
+		return ""
 	}
 
-	for key, value := range m {
-		fmt.Printf("节点 %d 上的数据共 %d 个。\n", key, value)
-		num += value
-	}
+	hash := int(m.hash([]byte(key)))
 
-	/* 对于 6个节点的情况，当虚拟节点为真实节点值的 6 倍及6倍以上时，分部还算均匀。
+	// 二分查找虚拟节点，找到 hash 大于的第一个节点。
+	index := sort.Search(len(m.keys), func(i int) bool {
+		tmp := m.keys[i]
+		fmt.Println(tmp)
+		return m.keys[i] >= hash
+	})
 
-	节点 5 上的数据共 1618900 个。
-	节点 3 上的数据共 2251837 个。
-	节点 4 上的数据共 2293730 个。
-	节点 6 上的数据共 1466977 个。
-	节点 2 上的数据共 1278352 个。
-	节点 1 上的数据共 1090204 个。
-	*/
+	// 为什么要取模？
+	// 当 hash 大于所有节点 hash 时，返回 index 就等于 len(m.keys)，keys[index] 已经越界。
+	// 因为是一个环，此时应该返回第一个节点 m.keys[0]，然后通过节点名，获取真实节点名 m.hashMap[m.keys[0]]。
+	return m.hashMap[m.keys[index%len(m.keys)]]
 }
\ No newline at end of file
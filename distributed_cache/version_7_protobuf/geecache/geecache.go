@@ -3,6 +3,7 @@ package geecache
 import (
 	"fmt"
 	"geecache/geecache/geecachepb"
+	"geecache/geecache/policy"
 	"geecache/geecache/singleflight"
 	"log"
 	"sync"
@@ -35,7 +36,18 @@ var (
 	groups  = make(map[string]*Group)
 )
 
-func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
+// GroupOption NewGroup 的可选配置
+type GroupOption func(g *Group)
+
+// WithPolicy 指定缓存的淘汰策略，默认为 LRU。
+// 例如有大量顺序扫描的批处理任务时，可以使用 geecache.WithPolicy(policy.NewTinyLFU)，避免热点数据被冲掉
+func WithPolicy(newPolicy policy.Factory) GroupOption {
+	return func(g *Group) {
+		g.mainCache.newPolicy = newPolicy
+	}
+}
+
+func NewGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption) *Group {
 	if getter == nil {
 		panic("nil Getter")
 	}
@@ -49,6 +61,9 @@ func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
 		mainCache: cache{cacheBytes: cacheBytes},
 		loader:    &singleflight.Group{},
 	}
+	for _, opt := range opts {
+		opt(g)
+	}
 	groups[name] = g
 	return g
 }
@@ -2,6 +2,7 @@ package geecache
 
 import (
 	"fmt"
+	"geecache/geecache/policy"
 	"log"
 	"net/http"
 	"testing"
@@ -56,6 +57,29 @@ func TestGet(t *testing.T) {
 	fmt.Println(view.String())
 }
 
+// TestGetWithPolicy 测试使用其他淘汰策略时，缓存命中后不再回源
+func TestGetWithPolicy(t *testing.T) {
+	loadCounts := make(map[string]int, len(db))
+	gee := NewGroup("scores-tinylfu", 1024, GetterFunc(
+		func(key string) ([]byte, error) {
+			loadCounts[key]++
+			return []byte(db[key]), nil
+		}), WithPolicy(policy.NewTinyLFU))
+
+	for i := 0; i < 3; i++ {
+		for k, v := range db {
+			if view, err := gee.Get(k); err != nil || view.String() != v {
+				t.Fatalf("failed to get value of %s", k)
+			}
+		}
+	}
+	for k, n := range loadCounts {
+		if n != 1 {
+			t.Fatalf("cache %s miss, loaded %d times", k, n)
+		}
+	}
+}
+
 func TestHTTP(t *testing.T) {
 	NewGroup("scores", 1024, GetterFunc(
 		func(key string) ([]byte, error) {
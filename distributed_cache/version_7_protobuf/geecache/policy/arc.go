@@ -0,0 +1,111 @@
+package policy
+
+// arc 自适应替换缓存（Adaptive Replacement Cache）。
+// T1 保存只访问过一次的缓存，T2 保存访问过多次的缓存，B1、B2 分别记录最近从 T1、T2 淘汰的 key（幽灵缓存）。
+// 命中 B1 说明 T1 太小，增大 T1 的目标大小 p；命中 B2 说明 T2 太小，减小 p。
+// 一次性的顺序扫描只会进入 T1，不会冲掉 T2 中的热点数据。这里按字节数而不是条数计算容量
+type arc struct {
+	maxBytes  int64
+	p         int64 // T1 的目标字节数
+	t1, t2    *segment
+	b1, b2    *segment
+	cache     map[string]*entry // 包括 B1、B2 中的幽灵缓存
+	onEvicted func(key string, value Value)
+}
+
+// NewARC 创建 ARC 淘汰策略
+func NewARC(maxBytes int64, onEvicted func(key string, value Value)) Policy {
+	return &arc{
+		maxBytes:  maxBytes,
+		t1:        newSegment(),
+		t2:        newSegment(),
+		b1:        newSegment(),
+		b2:        newSegment(),
+		cache:     make(map[string]*entry),
+		onEvicted: onEvicted,
+	}
+}
+
+func (c *arc) Add(key string, value Value) {
+	e, ok := c.cache[key]
+	size := sizeOf(key, value)
+	hitB2 := false
+	switch {
+	case ok && (e.seg == c.t1 || e.seg == c.t2):
+		e.resize(value)
+		e.seg.remove(e)
+		c.t2.pushFront(e)
+	case ok && e.seg == c.b1: // T1 淘汰得太早，增大 T1
+		c.p = min(c.maxBytes, c.p+max(size, size*c.b2.bytes/c.b1.bytes))
+		c.b1.remove(e)
+		e.value, e.size = value, size
+		c.t2.pushFront(e)
+	case ok && e.seg == c.b2: // T2 淘汰得太早，减小 T1
+		c.p = max(0, c.p-max(size, size*c.b1.bytes/c.b2.bytes))
+		c.b2.remove(e)
+		e.value, e.size = value, size
+		c.t2.pushFront(e)
+		hitB2 = true
+	default:
+		e = &entry{key: key, value: value, size: size}
+		c.t1.pushFront(e)
+		c.cache[key] = e
+	}
+	c.replace(hitB2)
+}
+
+func (c *arc) Get(key string) (value Value, ok bool) {
+	e, ok := c.cache[key]
+	if !ok || e.value == nil { // 幽灵缓存没有值
+		return nil, false
+	}
+	if e.seg == c.t2 {
+		c.t2.moveToFront(e)
+	} else { // 第二次访问，从 T1 移到 T2
+		c.t1.remove(e)
+		c.t2.pushFront(e)
+	}
+	return e.value, true
+}
+
+func (c *arc) Len() int {
+	return c.t1.len() + c.t2.len()
+}
+
+// replace 超过容量时，T1 大于目标大小 p 则淘汰 T1，否则淘汰 T2，淘汰的 key 进入对应的幽灵链表
+func (c *arc) replace(hitB2 bool) {
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.t1.bytes+c.t2.bytes > c.maxBytes {
+		if c.t1.len() > 0 && (c.t1.bytes > c.p || (hitB2 && c.t1.bytes == c.p) || c.t2.len() == 0) {
+			c.evict(c.t1, c.b1)
+		} else {
+			c.evict(c.t2, c.b2)
+		}
+	}
+	// 幽灵缓存只保存最近淘汰的 key：T1+B1 不超过容量，总量不超过两倍容量
+	for c.t1.bytes+c.b1.bytes > c.maxBytes && c.b1.len() > 0 {
+		c.dropGhost(c.b1)
+	}
+	for c.t1.bytes+c.t2.bytes+c.b1.bytes+c.b2.bytes > 2*c.maxBytes && c.b2.len() > 0 {
+		c.dropGhost(c.b2)
+	}
+}
+
+// evict 淘汰 from 尾部的缓存，只保留 key 放入幽灵链表 ghost
+func (c *arc) evict(from, ghost *segment) {
+	e := from.back()
+	from.remove(e)
+	if c.onEvicted != nil {
+		c.onEvicted(e.key, e.value)
+	}
+	e.value = nil
+	ghost.pushFront(e)
+}
+
+func (c *arc) dropGhost(ghost *segment) {
+	e := ghost.back()
+	ghost.remove(e)
+	delete(c.cache, e.key)
+}
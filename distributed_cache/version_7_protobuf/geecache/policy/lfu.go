@@ -0,0 +1,110 @@
+package policy
+
+import "container/list"
+
+// lfu 最不经常使用，淘汰访问次数最少的缓存，次数相同时淘汰最久没有访问的。
+// 相同访问次数的缓存放在同一个桶中，桶按次数从小到大排列，添加、访问、淘汰都是 O(1)
+type lfu struct {
+	maxBytes  int64
+	nBytes    int64
+	buckets   *list.List // 元素为 *lfuBucket，按访问次数从小到大排列
+	cache     map[string]*lfuEntry
+	onEvicted func(key string, value Value)
+}
+
+// lfuBucket 访问次数相同的缓存，链表头部是最近访问的
+type lfuBucket struct {
+	freq    int
+	entries *list.List
+}
+
+type lfuEntry struct {
+	key    string
+	value  Value
+	bucket *list.Element // 所在的桶
+	elem   *list.Element // 在桶中的位置
+}
+
+// NewLFU 创建 O(1) 的 LFU 淘汰策略
+func NewLFU(maxBytes int64, onEvicted func(key string, value Value)) Policy {
+	return &lfu{
+		maxBytes:  maxBytes,
+		buckets:   list.New(),
+		cache:     make(map[string]*lfuEntry),
+		onEvicted: onEvicted,
+	}
+}
+
+func (c *lfu) Add(key string, value Value) {
+	if e, ok := c.cache[key]; ok {
+		c.nBytes += int64(value.Len()) - int64(e.value.Len())
+		e.value = value
+		c.increment(e)
+	} else {
+		size := sizeOf(key, value)
+		// 先淘汰再插入，否则新加入的缓存访问次数最少，会被立即淘汰
+		for c.maxBytes != 0 && c.nBytes+size > c.maxBytes && len(c.cache) > 0 {
+			c.removeLeast()
+		}
+		front := c.buckets.Front()
+		if front == nil || front.Value.(*lfuBucket).freq != 1 {
+			front = c.buckets.PushFront(&lfuBucket{freq: 1, entries: list.New()})
+		}
+		e := &lfuEntry{key: key, value: value, bucket: front}
+		e.elem = front.Value.(*lfuBucket).entries.PushFront(e)
+		c.cache[key] = e
+		c.nBytes += size
+	}
+	for c.maxBytes != 0 && c.nBytes > c.maxBytes { // 单条缓存超过容量
+		c.removeLeast()
+	}
+}
+
+func (c *lfu) Get(key string) (value Value, ok bool) {
+	if e, ok := c.cache[key]; ok {
+		c.increment(e)
+		return e.value, true
+	}
+	return
+}
+
+func (c *lfu) Len() int {
+	return len(c.cache)
+}
+
+// increment 访问次数加一，移动到下一个桶
+func (c *lfu) increment(e *lfuEntry) {
+	cur := e.bucket
+	freq := cur.Value.(*lfuBucket).freq + 1
+	next := cur.Next()
+	if next == nil || next.Value.(*lfuBucket).freq != freq {
+		next = c.buckets.InsertAfter(&lfuBucket{freq: freq, entries: list.New()}, cur)
+	}
+	c.removeFromBucket(e)
+	e.bucket = next
+	e.elem = next.Value.(*lfuBucket).entries.PushFront(e)
+}
+
+// removeFromBucket 从桶中删除，桶为空时删除桶
+func (c *lfu) removeFromBucket(e *lfuEntry) {
+	bucket := e.bucket.Value.(*lfuBucket)
+	bucket.entries.Remove(e.elem)
+	if bucket.entries.Len() == 0 {
+		c.buckets.Remove(e.bucket)
+	}
+}
+
+// removeLeast 淘汰访问次数最少的桶中最久没有访问的缓存
+func (c *lfu) removeLeast() {
+	front := c.buckets.Front()
+	if front == nil {
+		return
+	}
+	e := front.Value.(*lfuBucket).entries.Back().Value.(*lfuEntry)
+	c.removeFromBucket(e)
+	delete(c.cache, e.key)
+	c.nBytes -= sizeOf(e.key, e.value)
+	if c.onEvicted != nil {
+		c.onEvicted(e.key, e.value)
+	}
+}
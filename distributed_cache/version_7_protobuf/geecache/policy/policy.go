@@ -0,0 +1,31 @@
+// Package policy 提供可替换的缓存淘汰策略：LRU、LFU、ARC、2Q 和 W-TinyLFU。
+// 所有策略都按 key 和 value 的字节数计算容量，maxBytes 为 0 时不淘汰，均不是并发安全的，由调用方加锁
+package policy
+
+import "geecache/geecache/lru"
+
+// Value 缓存的值，通过 Len 计算占用的字节数
+type Value = lru.Value
+
+// Policy 缓存淘汰策略
+type Policy interface {
+	// Add 添加或更新缓存，超过容量时按策略淘汰
+	Add(key string, value Value)
+	// Get 获取缓存，同时记录一次访问
+	Get(key string) (value Value, ok bool)
+	// Len 缓存数据的条数
+	Len() int
+}
+
+// Factory 创建淘汰策略，onEvicted 在缓存被淘汰时调用，可以为 nil
+type Factory func(maxBytes int64, onEvicted func(key string, value Value)) Policy
+
+// NewLRU 最近最少使用，淘汰最久没有访问的缓存，即 lru.Cache
+func NewLRU(maxBytes int64, onEvicted func(key string, value Value)) Policy {
+	return lru.New(maxBytes, onEvicted)
+}
+
+// sizeOf 一条缓存占用的字节数，与 lru.Cache 的计算方式一致
+func sizeOf(key string, value Value) int64 {
+	return int64(len(key)) + int64(value.Len())
+}
@@ -0,0 +1,220 @@
+package policy
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+type String string
+
+func (s String) Len() int {
+	return len(s)
+}
+
+var policies = []struct {
+	name string
+	new  Factory
+}{
+	{"LRU", NewLRU},
+	{"LFU", NewLFU},
+	{"ARC", NewARC},
+	{"2Q", NewTwoQueue},
+	{"TinyLFU", NewTinyLFU},
+}
+
+// TestCapacity 测试各策略的基本功能：命中、更新，以及总字节数不超过容量、淘汰时回调
+func TestCapacity(t *testing.T) {
+	for _, p := range policies {
+		t.Run(p.name, func(t *testing.T) {
+			var evicted []string
+			c := p.new(int64(100), func(key string, value Value) {
+				evicted = append(evicted, key)
+			})
+			c.Add("k1", String("v1"))
+			if v, ok := c.Get("k1"); !ok || v.(String) != "v1" {
+				t.Fatalf("cache hit k1=v1 failed, got %v %v", v, ok)
+			}
+			c.Add("k1", String("v2"))
+			if v, _ := c.Get("k1"); v.(String) != "v2" {
+				t.Fatalf("update k1 failed, got %v", v)
+			}
+			for i := 0; i < 100; i++ {
+				key := fmt.Sprintf("key%02d", i) // 每条缓存 10 字节
+				c.Add(key, String("value"))
+				c.Get(key)
+			}
+			if c.Len() > 10 {
+				t.Fatalf("expected at most 10 entries, got %d", c.Len())
+			}
+			if len(evicted)+c.Len() != 101 {
+				t.Fatalf("expected %d evictions, got %d", 101-c.Len(), len(evicted))
+			}
+		})
+	}
+}
+
+// TestUnlimited maxBytes 为 0 时不淘汰
+func TestUnlimited(t *testing.T) {
+	for _, p := range policies {
+		c := p.new(0, nil)
+		for i := 0; i < 1000; i++ {
+			c.Add(fmt.Sprint(i), String("value"))
+		}
+		if c.Len() != 1000 {
+			t.Fatalf("%s: expected 1000 entries, got %d", p.name, c.Len())
+		}
+	}
+}
+
+// TestLFU 访问次数最少的先被淘汰，次数相同时淘汰最久没有访问的
+func TestLFU(t *testing.T) {
+	c := NewLFU(int64(30), nil) // 3 条
+	c.Add("k1", String("12345678"))
+	c.Add("k2", String("12345678"))
+	c.Add("k3", String("12345678"))
+	c.Get("k1")
+	c.Get("k1")
+	c.Get("k3")
+	c.Add("k4", String("12345678")) // k2 只访问过一次
+	if _, ok := c.Get("k2"); ok {
+		t.Fatal("k2 should be evicted")
+	}
+	c.Add("k5", String("12345678")) // k4 与 k5 次数相同，k4 较早
+	if _, ok := c.Get("k4"); ok {
+		t.Fatal("k4 should be evicted")
+	}
+	for _, key := range []string{"k1", "k3", "k5"} {
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("%s should be cached", key)
+		}
+	}
+}
+
+// TestScanResistance 顺序扫描之后，ARC、2Q、W-TinyLFU 仍然保留大部分热点数据，而 LRU 中的大部分被冲掉
+func TestScanResistance(t *testing.T) {
+	const hot = 50
+	for _, p := range policies {
+		c := p.new(int64(100*16), nil) // 100 条
+		// 预热，热点数据与冷数据交替访问，缓存已满
+		for round := 0; round < 5; round++ {
+			for i := 0; i < hot; i++ {
+				access(c, fmt.Sprintf("hot-%04d", i))
+				access(c, fmt.Sprintf("cld-%d%03d", round, i))
+			}
+		}
+		for i := 0; i < 1000; i++ { // 扫描期间热点数据仍在被访问，但间隔比缓存容量长
+			access(c, fmt.Sprintf("scn-%04d", i))
+			if i%10 == 9 {
+				access(c, fmt.Sprintf("hot-%04d", i/10%hot))
+			}
+		}
+		kept := 0
+		for i := 0; i < hot; i++ {
+			if _, ok := c.Get(fmt.Sprintf("hot-%04d", i)); ok {
+				kept++
+			}
+		}
+		if p.name == "LRU" {
+			if kept > hot/2 {
+				t.Fatalf("LRU should be flushed by the scan, kept %d/%d", kept, hot)
+			}
+		} else if kept < hot*8/10 {
+			t.Fatalf("%s: expected most hot keys to survive the scan, kept %d/%d", p.name, kept, hot)
+		}
+	}
+}
+
+// access 模拟 geecache 的访问方式：未命中时回源并加入缓存，返回是否命中
+func access(c Policy, key string) bool {
+	if _, ok := c.Get(key); ok {
+		return true
+	}
+	c.Add(key, String("12345678"))
+	return false
+}
+
+// zipfTrace 服从 Zipf 分布的访问序列，少数 key 占大部分访问
+func zipfTrace(n int, keys uint64) []string {
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.1, 1, keys-1)
+	trace := make([]string, n)
+	for i := range trace {
+		trace[i] = fmt.Sprintf("k%07d", zipf.Uint64())
+	}
+	return trace
+}
+
+// scanTrace 在 Zipf 访问中周期性地插入只访问一次的顺序扫描，模拟批处理任务
+func scanTrace(n int, keys uint64) []string {
+	hot := zipfTrace(n, keys)
+	trace := make([]string, 0, n)
+	scan := 0
+	for i := 0; len(trace) < n; i++ {
+		trace = append(trace, hot[i])
+		if i%1000 == 999 {
+			for j := 0; j < 2000 && len(trace) < n; j++ {
+				trace = append(trace, fmt.Sprintf("s%07d", scan))
+				scan++
+			}
+		}
+	}
+	return trace
+}
+
+// BenchmarkHitRatio 在 Zipf 和扫描两种访问序列下比较各策略的命中率，容量为 1000 条缓存
+//
+//	go test -bench HitRatio -run ^$ ./geecache/policy
+func BenchmarkHitRatio(b *testing.B) {
+	traces := []struct {
+		name  string
+		trace []string
+	}{
+		{"Zipf", zipfTrace(1<<20, 100000)},
+		{"Scan", scanTrace(1<<20, 100000)},
+	}
+	for _, tr := range traces {
+		for _, p := range policies {
+			b.Run(tr.name+"/"+p.name, func(b *testing.B) {
+				c := p.new(int64(1000*16), nil)
+				hits := 0
+				for i := 0; i < b.N; i++ {
+					if access(c, tr.trace[i%len(tr.trace)]) {
+						hits++
+					}
+				}
+				b.ReportMetric(float64(hits)*100/float64(b.N), "hit%")
+			})
+		}
+	}
+}
+
+// TestTwoQueueScan 2Q 中只有 A1out 中的 key 再次加入才会进入 Am，扫描的数据即使马上被重复读取，也不会冲掉 Am 中的热点数据
+func TestTwoQueueScan(t *testing.T) {
+	c := NewTwoQueue(int64(100*16), nil) // 100 条，A1in 25 条，A1out 50 条
+	const hot = 20
+	for i := 0; i < hot; i++ {
+		access(c, fmt.Sprintf("hot-%04d", i))
+	}
+	for i := 0; i < 100; i++ { // 缓存满后按先进先出淘汰 A1in，最早加入的热点数据被记录到 A1out
+		access(c, fmt.Sprintf("cld-%04d", i))
+	}
+	for i := 0; i < hot; i++ { // 再次访问时从 A1out 进入 Am
+		if access(c, fmt.Sprintf("hot-%04d", i)) {
+			t.Fatalf("hot-%04d should have been evicted from A1in", i)
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("scn-%04d", i)
+		access(c, key)
+		if !access(c, key) { // 重复读取，仍然留在 A1in
+			t.Fatalf("%s should hit in A1in", key)
+		}
+	}
+	for i := 0; i < hot; i++ {
+		if _, ok := c.Get(fmt.Sprintf("hot-%04d", i)); !ok {
+			t.Fatalf("hot-%04d should survive the scan", i)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package policy
+
+import "container/list"
+
+// entry ARC、2Q、W-TinyLFU 中的一条缓存，幽灵缓存（只记录 key，不保存值）的 value 为 nil
+type entry struct {
+	key   string
+	value Value
+	size  int64
+	seg   *segment // 所在的链表
+	elem  *list.Element
+}
+
+// segment 记录总字节数的链表，头部是最近加入或访问的
+type segment struct {
+	ll    *list.List
+	bytes int64
+}
+
+func newSegment() *segment {
+	return &segment{ll: list.New()}
+}
+
+func (s *segment) pushFront(e *entry) {
+	e.seg = s
+	e.elem = s.ll.PushFront(e)
+	s.bytes += e.size
+}
+
+func (s *segment) remove(e *entry) {
+	s.ll.Remove(e.elem)
+	s.bytes -= e.size
+	e.seg = nil
+	e.elem = nil
+}
+
+func (s *segment) moveToFront(e *entry) {
+	s.ll.MoveToFront(e.elem)
+}
+
+// back 返回链表尾部，即最久没有访问的缓存，链表为空时返回 nil
+func (s *segment) back() *entry {
+	if ele := s.ll.Back(); ele != nil {
+		return ele.Value.(*entry)
+	}
+	return nil
+}
+
+func (s *segment) len() int {
+	return s.ll.Len()
+}
+
+// resize 更新缓存的值，并修正所在链表的字节数
+func (e *entry) resize(value Value) {
+	size := sizeOf(e.key, value)
+	e.seg.bytes += size - e.size
+	e.size = size
+	e.value = value
+}
@@ -0,0 +1,231 @@
+package policy
+
+import "hash/fnv"
+
+// tinyLFU W-TinyLFU 淘汰策略。新数据先进入占总容量 1% 的 LRU 窗口，从窗口淘汰时作为候选者，
+// 与主缓存中即将淘汰的数据比较访问频率，频率更高才能进入主缓存，否则直接丢弃。
+// 主缓存是分段 LRU：第一次进入的数据在 probation 段，再次访问时提升到占主缓存 80% 的 protected 段。
+// 访问频率由 Count-Min Sketch 估算，只出现过一次的 key 只记录在 doorkeeper 中，不占用 sketch 的计数器
+type tinyLFU struct {
+	maxBytes     int64
+	windowBytes  int64
+	mainBytes    int64
+	protectBytes int64
+	window       *segment
+	probation    *segment
+	protected    *segment
+	cache        map[string]*entry
+	sketch       *countMinSketch
+	onEvicted    func(key string, value Value)
+}
+
+// NewTinyLFU 创建 W-TinyLFU 淘汰策略，sketch 的大小按平均每条缓存 16 字节估算
+func NewTinyLFU(maxBytes int64, onEvicted func(key string, value Value)) Policy {
+	windowBytes := maxBytes / 100
+	mainBytes := maxBytes - windowBytes
+	return &tinyLFU{
+		maxBytes:     maxBytes,
+		windowBytes:  windowBytes,
+		mainBytes:    mainBytes,
+		protectBytes: mainBytes * 8 / 10,
+		window:       newSegment(),
+		probation:    newSegment(),
+		protected:    newSegment(),
+		cache:        make(map[string]*entry),
+		sketch:       newCountMinSketch(maxBytes / 16),
+		onEvicted:    onEvicted,
+	}
+}
+
+// Add 添加缓存，调用方通常在 Get 未命中后调用，访问频率已经在 Get 中记录，这里不再重复记录
+func (c *tinyLFU) Add(key string, value Value) {
+	if e, ok := c.cache[key]; ok {
+		e.resize(value)
+		c.promote(e)
+	} else {
+		e = &entry{key: key, value: value, size: sizeOf(key, value)}
+		c.window.pushFront(e)
+		c.cache[key] = e
+	}
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.window.bytes > c.windowBytes && c.window.len() > 0 {
+		candidate := c.window.back()
+		c.window.remove(candidate)
+		c.admit(candidate)
+	}
+	for c.protected.bytes > c.protectBytes { // protected 超过容量时降级到 probation
+		e := c.protected.back()
+		c.protected.remove(e)
+		c.probation.pushFront(e)
+	}
+	for c.probation.bytes+c.protected.bytes > c.mainBytes { // 更新后的值变大，主缓存超过容量
+		c.evict(c.victim())
+	}
+}
+
+func (c *tinyLFU) Get(key string) (value Value, ok bool) {
+	c.sketch.increment(key)
+	e, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	c.promote(e)
+	return e.value, true
+}
+
+func (c *tinyLFU) Len() int {
+	return len(c.cache)
+}
+
+// promote 访问缓存：窗口和 protected 中的移到头部，probation 中的提升到 protected
+func (c *tinyLFU) promote(e *entry) {
+	switch e.seg {
+	case c.window, c.protected:
+		e.seg.moveToFront(e)
+	case c.probation:
+		c.probation.remove(e)
+		c.protected.pushFront(e)
+		for c.maxBytes != 0 && c.protected.bytes > c.protectBytes && c.protected.len() > 1 {
+			demoted := c.protected.back()
+			c.protected.remove(demoted)
+			c.probation.pushFront(demoted)
+		}
+	}
+}
+
+// admit 从窗口淘汰的候选者与主缓存的淘汰者比较访问频率，频率更高时淘汰后者，否则丢弃候选者
+func (c *tinyLFU) admit(candidate *entry) {
+	if candidate.size > c.mainBytes {
+		c.discard(candidate)
+		return
+	}
+	for c.probation.bytes+c.protected.bytes+candidate.size > c.mainBytes {
+		victim := c.victim()
+		if c.sketch.estimate(candidate.key) <= c.sketch.estimate(victim.key) {
+			c.discard(candidate)
+			return
+		}
+		c.evict(victim)
+	}
+	c.probation.pushFront(candidate)
+}
+
+// victim 主缓存中下一个被淘汰的缓存，优先淘汰 probation
+func (c *tinyLFU) victim() *entry {
+	if e := c.probation.back(); e != nil {
+		return e
+	}
+	return c.protected.back()
+}
+
+func (c *tinyLFU) evict(e *entry) {
+	e.seg.remove(e)
+	c.discard(e)
+}
+
+// discard 删除已经不在任何链表中的缓存
+func (c *tinyLFU) discard(e *entry) {
+	delete(c.cache, e.key)
+	if c.onEvicted != nil {
+		c.onEvicted(e.key, e.value)
+	}
+}
+
+// sketchDepth Count-Min Sketch 的行数，估算值取各行的最小值
+const sketchDepth = 4
+
+// countMinSketch 估算 key 的访问频率，计数器最大为 15。
+// 记录的次数达到计数器数量的 10 倍时，所有计数器减半并清空 doorkeeper，使频率随时间衰减
+type countMinSketch struct {
+	counters   []uint8
+	width      uint64
+	doorkeeper []uint64 // 布隆过滤器，记录只出现过一次的 key
+	additions  int
+	sampleSize int
+}
+
+// newCountMinSketch 按预计的缓存条数创建，每行的宽度向上取整为 2 的幂
+func newCountMinSketch(entries int64) *countMinSketch {
+	width := uint64(64)
+	for int64(width) < entries && width < 1<<22 {
+		width <<= 1
+	}
+	return &countMinSketch{
+		counters:   make([]uint8, sketchDepth*width),
+		width:      width,
+		doorkeeper: make([]uint64, width/16), // 每行宽度的 4 倍个比特
+		sampleSize: int(10 * width),
+	}
+}
+
+// hash 计算两个哈希值，第 i 个位置为 h1 + i*h2
+func (s *countMinSketch) hash(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum64()
+	return sum & 0xffffffff, sum>>32 | 1
+}
+
+func (s *countMinSketch) increment(key string) {
+	h1, h2 := s.hash(key)
+	if !s.admitDoorkeeper(h1, h2) { // 第一次出现只记录在 doorkeeper 中
+		return
+	}
+	for i := uint64(0); i < sketchDepth; i++ {
+		idx := i*s.width + (h1+i*h2)&(s.width-1)
+		if s.counters[idx] < 15 {
+			s.counters[idx]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+func (s *countMinSketch) estimate(key string) int {
+	h1, h2 := s.hash(key)
+	least := uint8(15)
+	for i := uint64(0); i < sketchDepth; i++ {
+		if c := s.counters[i*s.width+(h1+i*h2)&(s.width-1)]; c < least {
+			least = c
+		}
+	}
+	if s.inDoorkeeper(h1, h2) {
+		return int(least) + 1
+	}
+	return int(least)
+}
+
+func (s *countMinSketch) doorkeeperBits(h1, h2 uint64) (uint64, uint64) {
+	n := uint64(len(s.doorkeeper)) * 64
+	return h1 % n, (h1 + h2) % n
+}
+
+func (s *countMinSketch) inDoorkeeper(h1, h2 uint64) bool {
+	a, b := s.doorkeeperBits(h1, h2)
+	return s.doorkeeper[a/64]&(1<<(a%64)) != 0 && s.doorkeeper[b/64]&(1<<(b%64)) != 0
+}
+
+// admitDoorkeeper key 已经在 doorkeeper 中时返回 true，否则加入 doorkeeper 并返回 false
+func (s *countMinSketch) admitDoorkeeper(h1, h2 uint64) bool {
+	if s.inDoorkeeper(h1, h2) {
+		return true
+	}
+	a, b := s.doorkeeperBits(h1, h2)
+	s.doorkeeper[a/64] |= 1 << (a % 64)
+	s.doorkeeper[b/64] |= 1 << (b % 64)
+	return false
+}
+
+func (s *countMinSketch) reset() {
+	for i := range s.counters {
+		s.counters[i] >>= 1
+	}
+	for i := range s.doorkeeper {
+		s.doorkeeper[i] = 0
+	}
+	s.additions /= 2
+}
@@ -0,0 +1,96 @@
+package policy
+
+// twoQ 2Q 淘汰策略。新数据先进入先进先出的 A1in，在 A1in 中被访问不改变顺序，从 A1in 淘汰时只把 key 记录到 A1out；
+// A1out 中的 key 再次加入时，才认为是热点数据，放入 LRU 的 Am。
+// 扫描的数据即使很快被重复读取，也只会经过 A1in，不会冲掉 Am
+type twoQ struct {
+	maxBytes  int64
+	inBytes   int64 // A1in 的容量，为总容量的 1/4
+	outBytes  int64 // A1out 记录的 key 对应的数据量，为总容量的 1/2
+	a1in      *segment
+	a1out     *segment
+	am        *segment
+	cache     map[string]*entry // 包括 A1out 中的幽灵缓存
+	onEvicted func(key string, value Value)
+}
+
+// NewTwoQueue 创建 2Q 淘汰策略
+func NewTwoQueue(maxBytes int64, onEvicted func(key string, value Value)) Policy {
+	return &twoQ{
+		maxBytes:  maxBytes,
+		inBytes:   maxBytes / 4,
+		outBytes:  maxBytes / 2,
+		a1in:      newSegment(),
+		a1out:     newSegment(),
+		am:        newSegment(),
+		cache:     make(map[string]*entry),
+		onEvicted: onEvicted,
+	}
+}
+
+func (c *twoQ) Add(key string, value Value) {
+	e, ok := c.cache[key]
+	switch {
+	case ok && e.seg == c.am:
+		e.resize(value)
+		c.am.moveToFront(e)
+	case ok && e.seg == c.a1in:
+		e.resize(value)
+	case ok && e.seg == c.a1out: // 最近被淘汰过又再次加入，放入 Am
+		c.a1out.remove(e)
+		e.value, e.size = value, sizeOf(key, value)
+		c.am.pushFront(e)
+	default:
+		e = &entry{key: key, value: value, size: sizeOf(key, value)}
+		c.a1in.pushFront(e)
+		c.cache[key] = e
+	}
+	c.reclaim()
+}
+
+func (c *twoQ) Get(key string) (value Value, ok bool) {
+	e, ok := c.cache[key]
+	if !ok || e.value == nil {
+		return nil, false
+	}
+	if e.seg == c.am {
+		c.am.moveToFront(e)
+	}
+	return e.value, true
+}
+
+func (c *twoQ) Len() int {
+	return c.a1in.len() + c.am.len()
+}
+
+// reclaim 超过容量时，A1in 超过自己的容量则淘汰 A1in 并记录到 A1out，否则淘汰 Am
+func (c *twoQ) reclaim() {
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.a1in.bytes+c.am.bytes > c.maxBytes {
+		if c.a1in.len() > 0 && (c.a1in.bytes > c.inBytes || c.am.len() == 0) {
+			e := c.a1in.back()
+			c.a1in.remove(e)
+			c.evicted(e)
+			e.value = nil
+			c.a1out.pushFront(e)
+		} else {
+			e := c.am.back()
+			c.am.remove(e)
+			delete(c.cache, e.key)
+			c.evicted(e)
+		}
+	}
+	for c.a1out.bytes > c.outBytes && c.a1out.len() > 0 {
+		e := c.a1out.back()
+		c.a1out.remove(e)
+		delete(c.cache, e.key)
+	}
+}
+
+func (c *twoQ) evicted(e *entry) {
+	if c.onEvicted != nil {
+		c.onEvicted(e.key, e.value)
+	}
+}
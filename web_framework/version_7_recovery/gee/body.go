@@ -0,0 +1,239 @@
+package gee
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrBodyTooLarge 请求体超过了 BodyLimit 或 Decompress 的限制，BindJSON 遇到该错误时返回 413
+var ErrBodyTooLarge = errors.New("gee: request body too large")
+
+// DefaultMaxDecompressedSize Decompress 默认允许的解压后请求体大小
+const DefaultMaxDecompressedSize = 10 << 20
+
+// BodyLimit 限制请求体最多 n 字节。Content-Length 超过限制时直接返回 413；
+// 否则使用 http.MaxBytesReader 包装请求体，读取超过限制时返回 ErrBodyTooLarge，并让服务端在响应后关闭连接。
+// 处理函数因此没有写入响应时，返回 413
+func BodyLimit(n int64) HandlerFunc {
+	return func(c *Context) {
+		if c.Req.ContentLength > n {
+			c.Fail(http.StatusRequestEntityTooLarge, ErrBodyTooLarge.Error())
+			return
+		}
+		if c.Req.Body == nil || c.Req.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		counter := &countingReader{ReadCloser: c.Req.Body}
+		// 传入原始的 http.ResponseWriter，超过限制时 net/http 才能关闭连接
+		body := &maxBytesBody{ReadCloser: http.MaxBytesReader(c.writer.ResponseWriter, counter, n), counter: counter, limit: n}
+		c.Req.Body = body
+		c.Next()
+		if body.exceeded {
+			failBodyTooLarge(c)
+		}
+	}
+}
+
+// countingReader 记录从原始请求体读取的字节数
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// maxBytesBody 将 http.MaxBytesReader 超过限制时的错误转换为 ErrBodyTooLarge
+type maxBytesBody struct {
+	io.ReadCloser
+	counter  *countingReader
+	limit    int64
+	exceeded bool
+}
+
+func (b *maxBytesBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.counter.n > b.limit {
+		b.exceeded = true
+		err = ErrBodyTooLarge
+	}
+	return n, err
+}
+
+// Decompress 解压 Content-Encoding 为 gzip 或 deflate 的请求体，解压后最多 DefaultMaxDecompressedSize 字节
+func Decompress() HandlerFunc {
+	return DecompressWithLimit(DefaultMaxDecompressedSize)
+}
+
+// DecompressWithLimit 解压请求体，解压后超过 maxSize 字节时返回 ErrBodyTooLarge，防止压缩炸弹。
+// 不支持的 Content-Encoding 返回 415，压缩格式错误返回 400
+func DecompressWithLimit(maxSize int64) HandlerFunc {
+	return func(c *Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.Req.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || c.Req.Body == nil || c.Req.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		var reader io.ReadCloser
+		switch encoding {
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(c.Req.Body)
+			if err != nil {
+				c.Fail(http.StatusBadRequest, fmt.Sprintf("invalid gzip body: %v", err))
+				return
+			}
+			reader = zr
+		case "deflate":
+			zr, err := newDeflateReader(c.Req.Body)
+			if err != nil {
+				c.Fail(http.StatusBadRequest, fmt.Sprintf("invalid deflate body: %v", err))
+				return
+			}
+			reader = zr
+		default:
+			c.Fail(http.StatusUnsupportedMediaType, "unsupported Content-Encoding "+encoding)
+			return
+		}
+
+		body := &decompressedBody{reader: reader, body: c.Req.Body, remaining: maxSize}
+		c.Req.Body = body
+		c.Req.Header.Del("Content-Encoding")
+		c.Req.Header.Del("Content-Length")
+		c.Req.ContentLength = -1 // 解压后的长度未知
+		c.Next()
+		if body.exceeded {
+			failBodyTooLarge(c)
+		}
+	}
+}
+
+// newDeflateReader HTTP 中的 deflate 是带 zlib 头的格式，但也有客户端直接发送原始的 deflate 数据，根据头部判断
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// decompressedBody 解压后的请求体，最多读取 remaining 字节
+type decompressedBody struct {
+	reader    io.ReadCloser
+	body      io.ReadCloser // 原始的请求体
+	remaining int64
+	exceeded  bool
+}
+
+func (b *decompressedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 { // 多读 1 个字节，用于判断是否超过限制
+		p = p[:b.remaining+1]
+	}
+	n, err := b.reader.Read(p)
+	if int64(n) > b.remaining {
+		b.exceeded = true
+		return int(b.remaining), ErrBodyTooLarge
+	}
+	b.remaining -= int64(n)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("gee: decompress request body: %w", err)
+	}
+	return n, err
+}
+
+func (b *decompressedBody) Close() error {
+	b.reader.Close()
+	return b.body.Close()
+}
+
+// failBodyTooLarge 请求体超过限制而处理函数没有写入响应时，返回 413
+func failBodyTooLarge(c *Context) {
+	if !c.Written() {
+		c.Fail(http.StatusRequestEntityTooLarge, ErrBodyTooLarge.Error())
+	}
+}
+
+// RequireContentType 要求带有请求体的请求使用指定的 Content-Type，否则返回 415。
+// types 可以是 application/json 这样的完整类型，也可以是 text/* 这样的通配类型，参数（例如 charset）不参与比较
+func RequireContentType(types ...string) HandlerFunc {
+	allowed := make([]string, len(types))
+	for i, t := range types {
+		allowed[i] = strings.ToLower(t)
+	}
+
+	return func(c *Context) {
+		if !hasBody(c.Req) {
+			c.Next()
+			return
+		}
+		mediaType, _, err := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+		if err != nil || !matchMediaType(mediaType, allowed) {
+			c.SetHeader("Accept", strings.Join(types, ", "))
+			c.Fail(http.StatusUnsupportedMediaType, "unsupported Content-Type, expected "+strings.Join(types, " or "))
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasBody(req *http.Request) bool {
+	return req.ContentLength > 0 || (req.ContentLength < 0 && req.Body != nil && req.Body != http.NoBody)
+}
+
+func matchMediaType(mediaType string, allowed []string) bool {
+	for _, t := range allowed {
+		if t == mediaType {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShouldBindJSON 将 JSON 请求体解码到 obj，出错时只返回错误，不写入响应
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	if c.Req.Body == nil || c.Req.Body == http.NoBody {
+		return errors.New("gee: missing request body")
+	}
+	return json.NewDecoder(c.Req.Body).Decode(obj)
+}
+
+// BindJSON 将 JSON 请求体解码到 obj，出错时返回 400，请求体超过 BodyLimit 或 Decompress 的限制时返回 413
+func (c *Context) BindJSON(obj interface{}) error {
+	err := c.ShouldBindJSON(obj)
+	if err != nil {
+		c.Fail(bodyErrorStatus(err), err.Error())
+	}
+	return err
+}
+
+// bodyErrorStatus 读取请求体出错时应当返回的状态码
+func bodyErrorStatus(err error) int {
+	if errors.Is(err, ErrBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,156 @@
+package gee
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newBindEngine(middlewares ...HandlerFunc) *Engine {
+	r := New()
+	r.Use(middlewares...)
+	r.POST("/", func(c *Context) {
+		var obj map[string]interface{}
+		if c.BindJSON(&obj) != nil {
+			return
+		}
+		c.JSON(http.StatusOK, obj)
+	})
+	return r
+}
+
+func TestBodyLimit(t *testing.T) {
+	r := newBindEngine(BodyLimit(16))
+	cases := []struct {
+		body    string
+		chunked bool // 不设置 Content-Length，只能在读取时发现超过限制
+		code    int
+	}{
+		{`{"a":1}`, false, http.StatusOK},
+		{`{"a":"0123456789abcdef"}`, false, http.StatusRequestEntityTooLarge},
+		{`{"a":"0123456789abcdef"}`, true, http.StatusRequestEntityTooLarge},
+		{`{"a":1`, true, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(tc.body))
+		if tc.chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Fatalf("%q chunked=%v: expected %d, got %d %s", tc.body, tc.chunked, tc.code, w.Code, w.Body.String())
+		}
+	}
+
+	// 处理函数忽略了读取错误时，也返回 413
+	r = New()
+	r.Use(BodyLimit(4))
+	r.POST("/", func(c *Context) {
+		_, _ = ioutil.ReadAll(c.Req.Body)
+	})
+	req := httptest.NewRequest("POST", "/", strings.NewReader("0123456789"))
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+}
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	r := newBindEngine(DecompressWithLimit(1024))
+	bomb := append(append([]byte(`{"a":"`), bytes.Repeat([]byte("0"), 1<<20)...), `"}`...)
+
+	cases := []struct {
+		encoding, header string
+		body             []byte
+		code             int
+	}{
+		{"gzip", "gzip", []byte(`{"a":"gzip"}`), http.StatusOK},
+		{"deflate", "deflate", []byte(`{"a":"zlib"}`), http.StatusOK},
+		{"raw-deflate", "deflate", []byte(`{"a":"raw"}`), http.StatusOK},
+		{"gzip", "gzip", bomb, http.StatusRequestEntityTooLarge},
+		{"gzip", "br", []byte(`{}`), http.StatusUnsupportedMediaType},
+	}
+	for _, tc := range cases {
+		data := compress(t, tc.encoding, tc.body)
+		if len(tc.body) > 1024 && len(data) > 8<<10 {
+			t.Fatalf("bomb should compress well, got %d bytes", len(data))
+		}
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(data))
+		req.Header.Set("Content-Encoding", tc.header)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d %s", tc.encoding, tc.code, w.Code, w.Body.String())
+		}
+		if tc.code == http.StatusOK && !strings.Contains(w.Body.String(), string(tc.body[6:len(tc.body)-2])) {
+			t.Fatalf("%s: unexpected body %s", tc.encoding, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid gzip: expected 400, got %d", w.Code)
+	}
+}
+
+func TestRequireContentType(t *testing.T) {
+	r := New()
+	r.Use(RequireContentType("application/json", "text/*"))
+	r.Any("/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	cases := []struct {
+		method, contentType, body string
+		code                      int
+	}{
+		{"POST", "application/json; charset=utf-8", "{}", http.StatusOK},
+		{"POST", "Application/JSON", "{}", http.StatusOK},
+		{"POST", "text/plain", "hi", http.StatusOK},
+		{"POST", "application/xml", "<a/>", http.StatusUnsupportedMediaType},
+		{"POST", "", "{}", http.StatusUnsupportedMediaType},
+		{"GET", "", "", http.StatusOK}, // 没有请求体时不检查
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, "/", strings.NewReader(tc.body))
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Fatalf("%s %q: expected %d, got %d", tc.method, tc.contentType, tc.code, w.Code)
+		}
+	}
+}
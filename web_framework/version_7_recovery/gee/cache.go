@@ -0,0 +1,319 @@
+package gee
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse 缓存的响应
+type CachedResponse struct {
+	Status       int
+	Header       http.Header
+	Body         []byte
+	ETag         string
+	LastModified time.Time
+}
+
+// CacheStore 响应缓存的存储，可以替换为 Redis 等外部存储
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse, ttl time.Duration)
+}
+
+// MemoryCacheStore 基于 LRU 的内存缓存，容量满时淘汰最久未使用的响应，过期的响应在读取时删除
+type MemoryCacheStore struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key     string
+	resp    *CachedResponse
+	expires time.Time
+}
+
+// NewMemoryCacheStore 创建最多保存 capacity 个响应的内存缓存
+func NewMemoryCacheStore(capacity int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (s *MemoryCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ele, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := ele.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expires) {
+		s.ll.Remove(ele)
+		delete(s.items, key)
+		return nil, false
+	}
+	s.ll.MoveToFront(ele)
+	return entry.resp, true
+}
+
+func (s *MemoryCacheStore) Set(key string, resp *CachedResponse, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ele, ok := s.items[key]; ok {
+		s.ll.MoveToFront(ele)
+		entry := ele.Value.(*memoryCacheEntry)
+		entry.resp, entry.expires = resp, time.Now().Add(ttl)
+		return
+	}
+	s.items[key] = s.ll.PushFront(&memoryCacheEntry{key: key, resp: resp, expires: time.Now().Add(ttl)})
+	for s.capacity > 0 && s.ll.Len() > s.capacity {
+		oldest := s.ll.Back()
+		s.ll.Remove(oldest)
+		delete(s.items, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// CacheOption Cache 中间件的可选配置
+type CacheOption func(cfg *cacheConfig)
+
+type cacheConfig struct {
+	queryParams []string // 参与缓存键计算的查询参数，为 nil 时使用全部查询参数
+	vary        []string // 参与缓存键计算的请求头，同时会写入响应的 Vary 头
+}
+
+// CacheQuery 只使用指定的查询参数计算缓存键，其余的查询参数（例如统计用的 utm_source）不影响缓存
+func CacheQuery(params ...string) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.queryParams = append([]string{}, params...)
+	}
+}
+
+// CacheVary 按指定的请求头区分缓存，例如 CacheVary("Accept-Language")
+func CacheVary(headers ...string) CacheOption {
+	return func(cfg *cacheConfig) {
+		for _, h := range headers {
+			cfg.vary = append(cfg.vary, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+// Cache 缓存 GET 和 HEAD 请求的响应，ttl 内相同的请求直接返回缓存的响应，不再执行处理函数。
+// 响应会带上 ETag 和 Last-Modified，请求携带 If-None-Match 或 If-Modified-Since 且内容未变化时返回 304。
+// 以下情况不缓存：请求带有 Cache-Control: no-store、响应状态码不是 2xx、响应设置了 Cookie 或 Cache-Control: no-store/private。
+func Cache(store CacheStore, ttl time.Duration, opts ...CacheOption) HandlerFunc {
+	cfg := &cacheConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(c *Context) {
+		if c.Method != http.MethodGet && c.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+		if hasDirective(c.Req.Header.Get("Cache-Control"), "no-store") {
+			c.Next()
+			return
+		}
+
+		key := cfg.key(c.Req)
+		if resp, ok := store.Get(key); ok {
+			c.SetHeader("X-Cache", "HIT")
+			writeCachedResponse(c, resp)
+			c.Abort()
+			return
+		}
+
+		// 将处理函数的输出写入缓冲区，执行完毕后再决定是否缓存
+		writer := c.Writer
+		buffer := &cacheWriter{header: make(http.Header)}
+		c.Writer = buffer
+		defer func() { // 处理函数 panic 时恢复 Writer，Recovery 的响应才能发送给客户端，且不缓存
+			c.Writer = writer
+		}()
+		c.Next()
+		c.Writer = writer
+
+		status := buffer.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		resp := &CachedResponse{
+			Status:       status,
+			Header:       buffer.header,
+			Body:         buffer.body.Bytes(),
+			ETag:         buffer.header.Get("ETag"),
+			LastModified: time.Now().UTC().Truncate(time.Second),
+		}
+		if lm, err := http.ParseTime(buffer.header.Get("Last-Modified")); err == nil {
+			resp.LastModified = lm
+		}
+		if resp.ETag == "" {
+			sum := sha1.Sum(resp.Body)
+			resp.ETag = `"` + hex.EncodeToString(sum[:]) + `"`
+		}
+		if len(cfg.vary) > 0 {
+			mergeVary(resp.Header, cfg.vary)
+		}
+
+		if cacheable(resp) {
+			store.Set(key, resp, ttl)
+		}
+		c.SetHeader("X-Cache", "MISS")
+		writeCachedResponse(c, resp)
+	}
+}
+
+// key 由请求方法、Host、路径、查询参数和 Vary 请求头组成。不同 Host 可能由不同的路由处理（见 Engine.Host），不能共用缓存
+func (cfg *cacheConfig) key(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteString(" ")
+	b.WriteString(strings.ToLower(req.Host))
+	b.WriteString(req.URL.Path)
+
+	query := req.URL.Query()
+	if cfg.queryParams != nil {
+		selected := make(url.Values)
+		for _, name := range cfg.queryParams {
+			if values, ok := query[name]; ok {
+				selected[name] = values
+			}
+		}
+		query = selected
+	}
+	if len(query) > 0 {
+		b.WriteString("?")
+		b.WriteString(query.Encode()) // Encode 按键排序，参数顺序不影响缓存键
+	}
+
+	for _, name := range cfg.vary {
+		b.WriteString("\n")
+		b.WriteString(name)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(req.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+func cacheable(resp *CachedResponse) bool {
+	if resp.Status < 200 || resp.Status >= 300 {
+		return false
+	}
+	if len(resp.Header.Values("Set-Cookie")) > 0 { // 带 Cookie 的响应是针对某个用户的，不能共享
+		return false
+	}
+	cacheControl := resp.Header.Get("Cache-Control")
+	return !hasDirective(cacheControl, "no-store") && !hasDirective(cacheControl, "private")
+}
+
+// writeCachedResponse 输出响应，满足条件请求时只返回 304
+func writeCachedResponse(c *Context, resp *CachedResponse) {
+	header := c.Writer.Header()
+	for key, values := range resp.Header { // 复制一份，并发命中的请求不能共用缓存中的切片
+		header[key] = append([]string(nil), values...)
+	}
+	header.Set("ETag", resp.ETag)
+	header.Set("Last-Modified", resp.LastModified.UTC().Format(http.TimeFormat))
+
+	if resp.Status == http.StatusOK && notModified(c.Req, resp) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Status(resp.Status)
+	if c.Method != http.MethodHead {
+		_, _ = c.Writer.Write(resp.Body)
+	}
+}
+
+// notModified 判断条件请求是否满足，If-None-Match 优先于 If-Modified-Since
+func notModified(req *http.Request, resp *CachedResponse) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(resp.ETag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims, err := http.ParseTime(req.Header.Get("If-Modified-Since")); err == nil {
+		return !resp.LastModified.Truncate(time.Second).After(ims)
+	}
+	return false
+}
+
+// mergeVary 将 names 合并到响应已有的 Vary 头中，忽略已经存在的请求头
+func mergeVary(header http.Header, names []string) {
+	var vary []string
+	exists := make(map[string]bool)
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name != "" && !exists[name] {
+				exists[name] = true
+				vary = append(vary, name)
+			}
+		}
+	}
+	for _, name := range names {
+		if !exists[name] {
+			exists[name] = true
+			vary = append(vary, name)
+		}
+	}
+	header.Set("Vary", strings.Join(vary, ", "))
+}
+
+// hasDirective 判断 Cache-Control 中是否包含某个指令
+func hasDirective(cacheControl string, directive string) bool {
+	for _, d := range strings.Split(cacheControl, ",") {
+		d = strings.TrimSpace(d)
+		if i := strings.IndexByte(d, '='); i >= 0 {
+			d = d[:i]
+		}
+		if strings.EqualFold(d, directive) {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheWriter 将响应写入缓冲区，而不是直接发送给客户端
+type cacheWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *cacheWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *cacheWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *cacheWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
+}
@@ -0,0 +1,137 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	calls := 0
+	r := New()
+	r.Use(Cache(NewMemoryCacheStore(10), time.Minute, CacheQuery("page")))
+	r.GET("/list", func(c *Context) {
+		calls++
+		c.JSON(http.StatusOK, H{"page": c.Query("page")})
+	})
+	r.GET("/missing", func(c *Context) {
+		calls++
+		c.String(http.StatusNotFound, "not found")
+	})
+
+	do := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := do("/list?page=1&utm=a", nil)
+	if first.Code != http.StatusOK || first.Header().Get("X-Cache") != "MISS" || first.Header().Get("ETag") == "" {
+		t.Fatalf("unexpected first response %d %v", first.Code, first.Header())
+	}
+	second := do("/list?utm=b&page=1", nil)
+	if second.Header().Get("X-Cache") != "HIT" || second.Body.String() != first.Body.String() || calls != 1 {
+		t.Fatalf("expect cache hit, calls=%d, headers=%v", calls, second.Header())
+	}
+	if second.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expect cached headers, got %v", second.Header())
+	}
+
+	etag := first.Header().Get("ETag")
+	if w := do("/list?page=1", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("expect 304, got %d", w.Code)
+	}
+	lastModified := first.Header().Get("Last-Modified")
+	if w := do("/list?page=1", http.Header{"If-Modified-Since": {lastModified}}); w.Code != http.StatusNotModified {
+		t.Fatalf("expect 304, got %d", w.Code)
+	}
+
+	if w := do("/list?page=1", http.Header{"Cache-Control": {"no-store"}}); w.Header().Get("X-Cache") != "" || calls != 2 {
+		t.Fatalf("expect cache bypass, calls=%d", calls)
+	}
+
+	do("/missing", nil)
+	do("/missing", nil)
+	if calls != 4 {
+		t.Fatalf("non-2xx response should not be cached, calls=%d", calls)
+	}
+}
+
+func TestMemoryCacheStore(t *testing.T) {
+	s := NewMemoryCacheStore(2)
+	s.Set("a", &CachedResponse{Status: 200}, time.Minute)
+	s.Set("b", &CachedResponse{Status: 200}, time.Minute)
+	s.Get("a")
+	s.Set("c", &CachedResponse{Status: 200}, time.Minute)
+	if _, ok := s.Get("b"); ok {
+		t.Fatal("least recently used entry should be evicted")
+	}
+	if _, ok := s.Get("a"); !ok {
+		t.Fatal("recently used entry should be kept")
+	}
+
+	s.Set("d", &CachedResponse{Status: 200}, -time.Second)
+	if _, ok := s.Get("d"); ok {
+		t.Fatal("expired entry should not be returned")
+	}
+}
+
+func TestCachePanic(t *testing.T) {
+	r := New()
+	r.Use(Recovery(), Cache(NewMemoryCacheStore(100), time.Minute, CacheVary("Accept-Language")))
+	calls := 0
+	r.GET("/panic", func(c *Context) {
+		calls++
+		panic("boom")
+	})
+	r.GET("/vary", func(c *Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		c.String(http.StatusOK, "ok")
+	})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("expected 500 from Recovery, got %d", w.Code)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("failed response should not be cached, handler called %d times", calls)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/vary", nil))
+	if got := w.Header().Get("Vary"); got != "Accept-Encoding, Accept-Language" {
+		t.Fatalf("Vary should be merged, got %q", got)
+	}
+}
+
+func TestCacheHost(t *testing.T) {
+	r := New()
+	tenants := r.Host("{tenant}.example.com")
+	tenants.Use(Cache(NewMemoryCacheStore(10), time.Minute))
+	tenants.GET("/profile", func(c *Context) {
+		c.String(http.StatusOK, "tenant %s", c.Param("tenant"))
+	})
+
+	for i := 0; i < 2; i++ {
+		for _, tenant := range []string{"a", "b"} {
+			req := httptest.NewRequest("GET", "/profile", nil)
+			req.Host = tenant + ".example.com"
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Body.String() != "tenant "+tenant {
+				t.Fatalf("%s.example.com: got %q, responses of different hosts must not share a cache entry", tenant, w.Body.String())
+			}
+			if cached := w.Header().Get("X-Cache") == "HIT"; cached != (i == 1) {
+				t.Fatalf("%s.example.com request %d: unexpected X-Cache %q", tenant, i, w.Header().Get("X-Cache"))
+			}
+		}
+	}
+}
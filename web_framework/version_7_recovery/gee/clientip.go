@@ -0,0 +1,190 @@
+package gee
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SetTrustedProxies 设置可信代理的地址，可以是 CIDR（例如 10.0.0.0/8）或单个 IP。
+// 只有请求来自可信代理时，才会使用 Forwarded、X-Forwarded-For、X-Real-IP 等请求头解析客户端地址，
+// 默认不信任任何代理，ClientIP 直接返回 TCP 连接的对端地址。
+func (engine *Engine) SetTrustedProxies(proxies []string) error {
+	var cidrs []*net.IPNet
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") { // 单个 IP 转换为 CIDR
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("gee: invalid trusted proxy %q", proxy)
+			}
+			if ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("gee: invalid trusted proxy %q: %v", proxy, err)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	engine.trustedProxies = cidrs
+	return nil
+}
+
+func (engine *Engine) isTrustedProxy(ip net.IP) bool {
+	for _, cidr := range engine.trustedProxies {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHop 请求经过的一跳，对应 Forwarded 中的一个元素或 X-Forwarded-For 中的一个地址
+type forwardedHop struct {
+	ip    net.IP // 这一跳的客户端地址，解析失败时为 nil
+	proto string
+	host  string
+}
+
+// remoteIP TCP 连接的对端地址
+func (c *Context) remoteIP() net.IP {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(c.Req.RemoteAddr))
+	if err != nil {
+		host = c.Req.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// clientHop 从右往左遍历代理链，跳过可信代理，返回第一个不可信的一跳，即真实的客户端。
+// 请求不是来自可信代理、或者代理链中出现无法解析的地址时，不使用请求头中的任何信息，返回 nil。
+func (c *Context) clientHop() *forwardedHop {
+	remote := c.remoteIP()
+	if remote == nil || c.engine == nil || !c.engine.isTrustedProxy(remote) {
+		return nil
+	}
+
+	hops := c.forwardedHops()
+	if len(hops) == 0 {
+		return nil
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if hops[i].ip == nil { // 无法解析的地址可能是伪造的，不再信任更左边的内容
+			return nil
+		}
+		if i == 0 || !c.engine.isTrustedProxy(hops[i].ip) {
+			return &hops[i]
+		}
+	}
+	return nil
+}
+
+// forwardedHops 按优先级依次尝试 Forwarded、X-Forwarded-For、X-Real-IP 请求头，解析出代理链
+func (c *Context) forwardedHops() []forwardedHop {
+	header := c.Req.Header
+	if values := header.Values("Forwarded"); len(values) > 0 {
+		return parseForwarded(strings.Join(values, ","))
+	}
+
+	if values := header.Values("X-Forwarded-For"); len(values) > 0 {
+		var hops []forwardedHop
+		for _, value := range strings.Split(strings.Join(values, ","), ",") {
+			hops = append(hops, forwardedHop{ip: parseHopIP(value)})
+		}
+		// X-Forwarded-Proto 和 X-Forwarded-Host 取最右边的值，即离服务端最近的可信代理设置的值
+		last := len(hops) - 1
+		hops[last].proto = lastValue(header.Values("X-Forwarded-Proto"))
+		hops[last].host = lastValue(header.Values("X-Forwarded-Host"))
+		for i := range hops[:last] {
+			hops[i].proto, hops[i].host = hops[last].proto, hops[last].host
+		}
+		return hops
+	}
+
+	if value := header.Get("X-Real-IP"); value != "" {
+		return []forwardedHop{{
+			ip:    parseHopIP(value),
+			proto: lastValue(header.Values("X-Forwarded-Proto")),
+			host:  lastValue(header.Values("X-Forwarded-Host")),
+		}}
+	}
+	return nil
+}
+
+// parseForwarded 解析 RFC 7239 的 Forwarded 请求头，例如：
+// Forwarded: for=192.0.2.60;proto=https;host=example.com, for="[2001:db8::17]:4711"
+func parseForwarded(value string) []forwardedHop {
+	var hops []forwardedHop
+	for _, element := range strings.Split(value, ",") {
+		var hop forwardedHop
+		for _, pair := range strings.Split(element, ";") {
+			i := strings.IndexByte(pair, '=')
+			if i < 0 {
+				continue
+			}
+			key := strings.ToLower(strings.TrimSpace(pair[:i]))
+			val := strings.Trim(strings.TrimSpace(pair[i+1:]), `"`)
+			switch key {
+			case "for":
+				hop.ip = parseHopIP(val)
+			case "proto":
+				hop.proto = strings.ToLower(val)
+			case "host":
+				hop.host = val
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// parseHopIP 解析一跳的地址，支持 "1.2.3.4"、"1.2.3.4:80"、"[::1]"、"[::1]:80" 等形式，
+// "unknown" 和 "_hidden" 这类隐藏地址返回 nil
+func parseHopIP(value string) net.IP {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	return net.ParseIP(strings.Trim(value, "[]"))
+}
+
+func lastValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	parts := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(parts[len(parts)-1])
+}
+
+// ClientIP 返回客户端的真实地址。请求来自可信代理时，从右往左解析 Forwarded、X-Forwarded-For、X-Real-IP，
+// 跳过可信代理，返回第一个不可信的地址，客户端伪造的请求头只会出现在更左边，因此不会被采用。
+func (c *Context) ClientIP() string {
+	if hop := c.clientHop(); hop != nil {
+		return hop.ip.String()
+	}
+	if ip := c.remoteIP(); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// Scheme 返回客户端请求使用的协议，http 或 https，请求来自可信代理时使用代理转发的协议
+func (c *Context) Scheme() string {
+	if hop := c.clientHop(); hop != nil && (hop.proto == "http" || hop.proto == "https") {
+		return hop.proto
+	}
+	if c.Req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host 返回客户端请求的 Host，请求来自可信代理时使用代理转发的 Host
+func (c *Context) Host() string {
+	if hop := c.clientHop(); hop != nil && hop.host != "" {
+		return hop.host
+	}
+	return c.Req.Host
+}
@@ -0,0 +1,86 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	cases := []struct {
+		name   string
+		remote string
+		header http.Header
+		want   string
+	}{
+		{"no proxy", "203.0.113.5:1234", nil, "203.0.113.5"},
+		{"untrusted remote ignores headers", "198.51.100.1:1234",
+			http.Header{"X-Forwarded-For": {"1.2.3.4"}, "X-Real-Ip": {"1.2.3.4"}}, "198.51.100.1"},
+		{"trusted proxy", "10.0.0.1:1234",
+			http.Header{"X-Forwarded-For": {"203.0.113.5"}}, "203.0.113.5"},
+		{"spoofed left entries are skipped", "10.0.0.1:1234",
+			http.Header{"X-Forwarded-For": {"1.2.3.4, 203.0.113.5"}}, "203.0.113.5"},
+		{"chain of trusted proxies", "10.0.0.1:1234",
+			http.Header{"X-Forwarded-For": {"1.2.3.4, 203.0.113.5", "10.0.0.2"}}, "203.0.113.5"},
+		{"invalid hop falls back to remote", "10.0.0.1:1234",
+			http.Header{"X-Forwarded-For": {"203.0.113.5, not-an-ip"}}, "10.0.0.1"},
+		{"x-real-ip", "10.0.0.1:1234",
+			http.Header{"X-Real-Ip": {"203.0.113.5"}}, "203.0.113.5"},
+		{"forwarded", "10.0.0.1:1234",
+			http.Header{"Forwarded": {`for=1.2.3.4, for="[2001:db8::17]:4711";proto=https, for=10.0.0.2`}}, "2001:db8::17"},
+		{"forwarded takes priority", "10.0.0.1:1234",
+			http.Header{"Forwarded": {"for=203.0.113.5"}, "X-Forwarded-For": {"1.2.3.4"}}, "203.0.113.5"},
+	}
+
+	r := New()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8", "::1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = c.remote
+		for k, v := range c.header {
+			req.Header[k] = v
+		}
+		ctx, _ := CreateTestContext(httptest.NewRecorder(), req)
+		ctx.engine.trustedProxies = r.trustedProxies
+		if got := ctx.ClientIP(); got != c.want {
+			t.Fatalf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Fatal("expect invalid cidr error")
+	}
+}
+
+func TestSchemeAndHost(t *testing.T) {
+	r := New()
+	_ = r.SetTrustedProxies([]string{"10.0.0.1"})
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "%s://%s", c.Scheme(), c.Host())
+	})
+
+	do := func(remote string, header http.Header) string {
+		req := httptest.NewRequest("GET", "http://internal/", nil)
+		req.RemoteAddr = remote
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	header := http.Header{"X-Forwarded-For": {"203.0.113.5"}, "X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"example.com"}}
+	if got := do("10.0.0.1:80", header); got != "https://example.com" {
+		t.Fatalf("trusted proxy: got %q", got)
+	}
+	if got := do("203.0.113.9:80", header); got != "http://internal" {
+		t.Fatalf("untrusted client: got %q", got)
+	}
+	forwarded := http.Header{"Forwarded": {"for=203.0.113.5;proto=https;host=shop.example.com"}}
+	if got := do("10.0.0.1:80", forwarded); got != "https://shop.example.com" {
+		t.Fatalf("forwarded: got %q", got)
+	}
+}
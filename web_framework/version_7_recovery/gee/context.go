@@ -3,9 +3,16 @@ package gee
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
+// defaultMultipartMemory 解析 multipart 表单时使用的内存上限，超出的部分写入临时文件
+const defaultMultipartMemory = 32 << 20
+
 type Context struct { // 暂且保存常用的参数
 	// origin objects
 	Req    *http.Request
@@ -16,24 +23,66 @@ type Context struct { // 暂且保存常用的参数
 	Params map[string]string
 	// response info
 	StatusCode int
+	// 处理过程中通过 c.Error 记录的错误
+	Errors []error
+	// 解析后的查询参数和表单，在 Context 的生命周期内只解析一次
+	queryCache url.Values
+	formCache  url.Values
 	// middleware
 	handlers []HandlerFunc
 	index    int
 	// engine pointer
 	engine *Engine
+	// 匹配上的路由地址，例如 /user/:id
+	fullPath string
+	// 记录响应状态码和大小，Writer 默认指向它，中间件替换 Writer 后它只记录实际发送给客户端的内容
+	writer *responseWriter
+	// 当前请求使用的翻译器，由 i18n 中间件设置
+	translator Translator
+	// 协商出的 API 版本，路由没有分版本时为空
+	version string
 }
 
 func NewContext(writer http.ResponseWriter, req *http.Request) *Context {
+	w := &responseWriter{ResponseWriter: writer}
 	return &Context{
 		Req:    req,
-		Writer: writer,
+		Writer: w,
 		Path:   req.URL.Path,
 		Method: req.Method,
 
-		index: -1,
+		index:  -1,
+		writer: w,
+	}
+}
+
+// Error 记录处理过程中的错误，不会中断处理，也不会写入响应，供 Tracing 等中间件在请求结束后读取
+func (c *Context) Error(err error) {
+	if err != nil {
+		c.Errors = append(c.Errors, err)
 	}
 }
 
+// FullPath 返回匹配上的路由地址，例如 /user/:id，未匹配任何路由时返回空字符串
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
+// Written 是否已经向客户端写入了响应
+func (c *Context) Written() bool {
+	return c.writer.status != 0
+}
+
+// ResponseStatus 返回已经发送给客户端的状态码，还未写入时返回 0
+func (c *Context) ResponseStatus() int {
+	return c.writer.status
+}
+
+// ResponseSize 返回已经发送给客户端的响应体字节数
+func (c *Context) ResponseSize() int {
+	return c.writer.size
+}
+
 func (c *Context) Next() {
 	c.index++
 	s := len(c.handlers)
@@ -42,18 +91,164 @@ func (c *Context) Next() {
 	}
 }
 
+// abortIndex 调用 Abort 后 index 被设置为该值，大于任何处理函数链的长度，用于区分正常执行完毕和中止
+const abortIndex = math.MaxInt16
+
+// Abort 跳过后续的处理函数，已经在执行的中间件中 c.Next() 之后的代码仍然会执行
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted 是否已经调用过 Abort 或 Fail
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// initQueryCache 解析一次查询参数并缓存，避免每次调用 Query 时重复解析 URL
+func (c *Context) initQueryCache() {
+	if c.queryCache == nil {
+		c.queryCache = c.Req.URL.Query()
+	}
+}
+
+// initFormCache 解析一次请求体中的表单并缓存，支持 application/x-www-form-urlencoded 和 multipart/form-data
+func (c *Context) initFormCache() {
+	if c.formCache != nil {
+		return
+	}
+	if err := c.Req.ParseMultipartForm(defaultMultipartMemory); err != nil && err != http.ErrNotMultipart {
+		c.Error(err)
+	}
+	c.formCache = c.Req.PostForm
+	if c.formCache == nil {
+		c.formCache = make(url.Values)
+	}
+}
+
+// PostForm 获取表单字段的第一个值，与 c.Req.FormValue(key) 一致：请求体中不存在时读取查询参数，
+// 都不存在时返回空字符串。只读取请求体请使用 GetPostForm
 func (c *Context) PostForm(key string) string {
-	return c.Req.FormValue(key)
+	if value, ok := c.GetPostForm(key); ok {
+		return value
+	}
+	return c.Query(key)
+}
+
+// DefaultPostForm 获取表单字段，不存在时返回 defaultValue
+func (c *Context) DefaultPostForm(key string, defaultValue string) string {
+	if value, ok := c.GetPostForm(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetPostForm 获取表单字段的第一个值，并返回字段是否存在
+func (c *Context) GetPostForm(key string) (string, bool) {
+	if values, ok := c.GetPostFormArray(key); ok {
+		return values[0], true
+	}
+	return "", false
+}
+
+// PostFormArray 获取表单字段的所有值，例如 tag=a&tag=b 得到 ["a", "b"]
+func (c *Context) PostFormArray(key string) []string {
+	values, _ := c.GetPostFormArray(key)
+	return values
+}
+
+func (c *Context) GetPostFormArray(key string) ([]string, bool) {
+	c.initFormCache()
+	values, ok := c.formCache[key]
+	return values, ok && len(values) > 0
+}
+
+// PostFormMap 获取方括号形式的表单字段，例如 user[name]=tom&user[age]=18 得到 {"name": "tom", "age": "18"}
+func (c *Context) PostFormMap(key string) map[string]string {
+	dict, _ := c.GetPostFormMap(key)
+	return dict
 }
 
+func (c *Context) GetPostFormMap(key string) (map[string]string, bool) {
+	c.initFormCache()
+	return bracketMap(c.formCache, key)
+}
+
+// Query 获取查询参数的第一个值，不存在时返回空字符串
 func (c *Context) Query(key string) string {
-	return c.Req.URL.Query().Get(key)
+	value, _ := c.GetQuery(key)
+	return value
+}
+
+// DefaultQuery 获取查询参数，不存在时返回 defaultValue，例如 c.DefaultQuery("page", "1")
+func (c *Context) DefaultQuery(key string, defaultValue string) string {
+	if value, ok := c.GetQuery(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetQuery 获取查询参数的第一个值，并返回参数是否存在，用于区分 ?q= 和没有 q 参数
+func (c *Context) GetQuery(key string) (string, bool) {
+	if values, ok := c.GetQueryArray(key); ok {
+		return values[0], true
+	}
+	return "", false
+}
+
+// QueryArray 获取查询参数的所有值，例如 ?tag=a&tag=b 得到 ["a", "b"]
+func (c *Context) QueryArray(key string) []string {
+	values, _ := c.GetQueryArray(key)
+	return values
+}
+
+func (c *Context) GetQueryArray(key string) ([]string, bool) {
+	c.initQueryCache()
+	values, ok := c.queryCache[key]
+	return values, ok && len(values) > 0
+}
+
+// QueryMap 获取方括号形式的查询参数，例如 ?filter[a]=1&filter[b]=2 得到 {"a": "1", "b": "2"}
+func (c *Context) QueryMap(key string) map[string]string {
+	dict, _ := c.GetQueryMap(key)
+	return dict
+}
+
+func (c *Context) GetQueryMap(key string) (map[string]string, bool) {
+	c.initQueryCache()
+	return bracketMap(c.queryCache, key)
+}
+
+// bracketMap 从 values 中取出 key[xxx] 形式的参数，组成 map，同一个键有多个值时取第一个
+func bracketMap(values url.Values, key string) (map[string]string, bool) {
+	dict := make(map[string]string)
+	exist := false
+	for k, v := range values {
+		i := strings.IndexByte(k, '[')
+		if i != len(key) || k[:i] != key || len(v) == 0 {
+			continue
+		}
+		if j := strings.IndexByte(k[i+1:], ']'); j >= 1 && i+1+j == len(k)-1 {
+			exist = true
+			dict[k[i+1:i+1+j]] = v[0]
+		}
+	}
+	return dict, exist
 }
 
 func (c *Context) Param(key string) string {
 	return c.Params[key]
 }
 
+// ParamInt 以 int 类型获取路由参数，通常与 :id<int> 约束搭配使用
+func (c *Context) ParamInt(key string) (int, error) {
+	return strconv.Atoi(c.Params[key])
+}
+
+// ParamInt64 以 int64 类型获取路由参数
+func (c *Context) ParamInt64(key string) (int64, error) {
+	return strconv.ParseInt(c.Params[key], 10, 64)
+}
+
 func (c *Context) SetHeader(key string, value string) {
 	c.Writer.Header().Set(key, value)
 }
@@ -72,7 +267,7 @@ func (c *Context) String(code int, format string, values ...interface{}) {
 }
 
 func (c *Context) JSON(code int, obj interface{}) {
-	c.SetHeader("Context-Type", "application/json")
+	c.SetHeader("Content-Type", "application/json")
 	c.Status(code)
 	encoder := json.NewEncoder(c.Writer)
 	if err := encoder.Encode(obj); err != nil {
@@ -88,16 +283,16 @@ func (c *Context) Data(code int, date []byte) {
 }
 
 func (c *Context) Fail(code int, err string) {
-	c.index = len(c.handlers)
+	c.Abort()
 	c.JSON(code, H{"message": err})
 }
 
 func (c *Context) HTML(code int, name string, data interface{}) {
-	c.SetHeader("Context-Type", "text/html")
+	c.SetHeader("Content-Type", "text/html")
 	c.Status(code)
 
 	// context 需要保存 Engine 指针，以便可以访问 htmlTemplates
-	err := c.engine.htmlTemplates.ExecuteTemplate(c.Writer, name, data)
+	err := c.htmlTemplate().ExecuteTemplate(c.Writer, name, data)
 	if err != nil {
 		c.Fail(500, err.Error())
 	}
@@ -0,0 +1,60 @@
+package gee
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQueryHelpers(t *testing.T) {
+	req := httptest.NewRequest("GET", "/search?tag=go&tag=web&q=&filter[lang]=zh&filter[year]=2024&filter=x&page=2", nil)
+	c, _ := CreateTestContext(httptest.NewRecorder(), req)
+
+	if c.Query("page") != "2" || c.DefaultQuery("size", "10") != "10" || c.DefaultQuery("page", "1") != "2" {
+		t.Fatal("unexpected Query/DefaultQuery result")
+	}
+	if value, ok := c.GetQuery("q"); !ok || value != "" {
+		t.Fatal("empty query param should exist")
+	}
+	if _, ok := c.GetQuery("missing"); ok {
+		t.Fatal("missing query param should not exist")
+	}
+	if got := c.QueryArray("tag"); !reflect.DeepEqual(got, []string{"go", "web"}) {
+		t.Fatalf("unexpected QueryArray %v", got)
+	}
+	if got := c.QueryMap("filter"); !reflect.DeepEqual(got, map[string]string{"lang": "zh", "year": "2024"}) {
+		t.Fatalf("unexpected QueryMap %v", got)
+	}
+
+	c.Req.URL.RawQuery = "page=3" // 解析结果已缓存
+	if c.Query("page") != "2" {
+		t.Fatal("query should be parsed only once")
+	}
+}
+
+func TestPostFormHelpers(t *testing.T) {
+	body := "tag=a&tag=b&user[name]=tom&user[age]=18&empty="
+	req := httptest.NewRequest("POST", "/?from=query", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c, _ := CreateTestContext(httptest.NewRecorder(), req)
+
+	if got := c.PostFormArray("tag"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("unexpected PostFormArray %v", got)
+	}
+	if c.PostForm("tag") != "a" || c.DefaultPostForm("missing", "x") != "x" || c.DefaultPostForm("empty", "x") != "" {
+		t.Fatal("unexpected PostForm/DefaultPostForm result")
+	}
+	if c.PostForm("from") != "query" {
+		t.Fatal("PostForm should fall back to query params like FormValue")
+	}
+	if _, ok := c.GetPostForm("from"); ok {
+		t.Fatal("GetPostForm should not read query params")
+	}
+	if got := c.PostFormMap("user"); !reflect.DeepEqual(got, map[string]string{"name": "tom", "age": "18"}) {
+		t.Fatalf("unexpected PostFormMap %v", got)
+	}
+	if _, ok := c.GetPostFormMap("missing"); ok {
+		t.Fatal("missing form map should not exist")
+	}
+}
@@ -0,0 +1,45 @@
+package gee
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// EnableDebugRoutes 在 group 下注册 pprof 和 expvar 页面，页面受 group 的中间件保护（例如鉴权）。
+// 例如 engine.EnableDebugRoutes(r.Group("/debug"))，注册的地址为：
+//
+//	/debug/pprof/          pprof 首页
+//	/debug/pprof/:profile  各类 profile，例如 heap、goroutine、profile、trace
+//	/debug/vars            expvar 变量
+func (engine *Engine) EnableDebugRoutes(group *RouterGroup) {
+	group.GET("/pprof", pprofIndex)
+	group.GET("/pprof/*profile", pprofHandler)
+	group.POST("/pprof/symbol", WrapF(pprof.Symbol)) // symbol 支持通过 POST 批量查询
+	group.GET("/vars", WrapH(expvar.Handler()))
+}
+
+// pprofIndex pprof 首页中的链接是相对地址，需要保证访问地址以 "/" 结尾
+func pprofIndex(c *Context) {
+	if !strings.HasSuffix(c.Req.URL.Path, "/") {
+		http.Redirect(c.Writer, c.Req, c.Req.URL.Path+"/", http.StatusMovedPermanently)
+		return
+	}
+	pprof.Index(c.Writer, c.Req)
+}
+
+func pprofHandler(c *Context) {
+	switch name := c.Param("profile"); name {
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Req)
+	case "profile":
+		pprof.Profile(c.Writer, c.Req)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Req)
+	case "trace":
+		pprof.Trace(c.Writer, c.Req)
+	default:
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Req)
+	}
+}
@@ -0,0 +1,70 @@
+package gee
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// NoRoute 设置分组下未匹配任何路由时的处理函数，例如 /api 分组返回 JSON 格式的 404，其余路径返回 HTML 页面。
+// 请求会使用路径前缀最长的、设置了 NoRoute 的分组，处理函数之前仍然会执行全局和分组中间件，因此日志、CORS 等中间件依然生效。
+func (group *RouterGroup) NoRoute(handlers ...HandlerFunc) {
+	group.noRoute = handlers
+}
+
+// NoMethod 设置路径匹配但请求方法不匹配时的处理函数，调用后这类请求会返回 405 并带上 Allow 响应头，
+// 未调用时按未匹配路由处理。不传入处理函数时使用默认的 405 响应。
+func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
+	engine.noMethod = handlers
+	engine.handleMethodNotAllowed = true
+}
+
+// fallbackHandlers 路由未匹配时使用的处理函数
+func (engine *Engine) fallbackHandlers(r *router, c *Context) []HandlerFunc {
+	if engine.handleMethodNotAllowed {
+		if allowed := r.allowedMethods(c.Path, c.Method); len(allowed) > 0 {
+			c.SetHeader("Allow", strings.Join(allowed, ", "))
+			if len(engine.noMethod) > 0 {
+				return engine.noMethod
+			}
+			return []HandlerFunc{defaultNoMethod}
+		}
+	}
+
+	var matched *RouterGroup
+	for _, group := range engine.groups {
+		if group.router != r || len(group.noRoute) == 0 || !strings.HasPrefix(c.Path, group.prefix+"/") {
+			continue
+		}
+		if matched == nil || len(group.prefix) > len(matched.prefix) {
+			matched = group
+		}
+	}
+	if matched != nil {
+		return matched.noRoute
+	}
+	return []HandlerFunc{defaultNoRoute}
+}
+
+// allowedMethods 返回能匹配 path 的其他请求方法
+func (r *router) allowedMethods(path string, exclude string) []string {
+	var allowed []string
+	for method, root := range r.roots {
+		if method == exclude {
+			continue
+		}
+		if n, _ := root.search(path); n != nil {
+			allowed = append(allowed, method)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+func defaultNoRoute(c *Context) {
+	c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Req.URL)
+}
+
+func defaultNoMethod(c *Context) {
+	c.String(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: %s %s\n", c.Method, c.Req.URL)
+}
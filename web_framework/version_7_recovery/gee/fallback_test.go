@@ -0,0 +1,69 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNoRoute(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) {
+		c.SetHeader("X-Request-Id", "1")
+	})
+	r.GET("/", func(c *Context) {})
+	r.NoRoute(func(c *Context) {
+		c.SetHeader("Content-Type", "text/html")
+		c.Data(http.StatusNotFound, []byte("<h1>Not Found</h1>"))
+	})
+	api := r.Group("/api")
+	api.GET("/users", func(c *Context) {})
+	api.NoRoute(func(c *Context) {
+		c.JSON(http.StatusNotFound, H{"error": "not found"})
+	})
+
+	cases := []struct {
+		path, contentType string
+	}{
+		{"/missing", "text/html"},
+		{"/api/missing", "application/json"},
+		{"/apimissing", "text/html"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+		if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != tc.contentType {
+			t.Fatalf("%s: got %d %q", tc.path, w.Code, w.Header().Get("Content-Type"))
+		}
+		if w.Header().Get("X-Request-Id") != "1" {
+			t.Fatalf("%s: global middleware should run before NoRoute", tc.path)
+		}
+	}
+}
+
+func TestNoMethod(t *testing.T) {
+	r := New()
+	r.GET("/users", func(c *Context) {})
+	r.POST("/users", func(c *Context) {})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/users", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("405 should be disabled by default, got %d", w.Code)
+	}
+
+	r.NoMethod(func(c *Context) {
+		c.JSON(http.StatusMethodNotAllowed, H{"error": "method not allowed"})
+	})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/users", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, POST" {
+		t.Fatalf("expect 405 with Allow header, got %d %v", w.Code, w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown path should still be 404, got %d", w.Code)
+	}
+}
@@ -2,9 +2,10 @@ package gee
 
 import (
 	"html/template"
+	"net"
 	"net/http"
 	"path"
-	"strings"
+	"sync"
 )
 
 type HandlerFunc func(ctx *Context)
@@ -15,37 +16,68 @@ type Engine struct {
 	*RouterGroup
 	router *router
 	groups []*RouterGroup
+	hosts  []*hostRouter // 按 Host 划分的路由，未匹配任何 Host 时使用默认的 router
+
+	trustedProxies []*net.IPNet // 可信代理，只有来自可信代理的请求才会解析 X-Forwarded-For 等请求头
+
+	noMethod               []HandlerFunc // 请求方法不匹配时的处理函数
+	handleMethodNotAllowed bool          // 是否区分 405 和 404，调用 NoMethod 后开启
+
+	// API 版本协商
+	versioning VersioningOptions
+	versions   map[string]*apiVersion // 通过 Version 创建的版本
+
+	// 请求生命周期钩子
+	onRequest      []Hook
+	onRouteMatched []Hook
+	onResponse     []Hook
+	onError        []ErrorHook
+
+	// for graceful shutdown
+	mu           sync.Mutex
+	server       *http.Server // Run 创建的 http.Server，Shutdown 时使用
+	onShutdown   []func()     // Shutdown 开始时执行的回调
+	shuttingDown bool
 
 	// for html render
 	htmlTemplates *template.Template // 将所有模板加载进内存
 	funcMap       template.FuncMap   // 模板的渲染函数(可自定义)
+
+	// for i18n render
+	htmlBase           *template.Template            // 从未执行过的模板，用于按语言克隆
+	localizedTemplates map[string]*template.Template // 按语言缓存的模板，t 函数使用对应语言的翻译器
+	templateMu         sync.Mutex
 }
 
 func New() *Engine {
 	engine := &Engine{router: newRouter()}
-	engine.RouterGroup = &RouterGroup{engine: engine}
+	engine.RouterGroup = &RouterGroup{engine: engine, router: engine.router}
 	engine.groups = []*RouterGroup{engine.RouterGroup}
 	return engine
 }
 
 func (engine *Engine) Run(addr string) error {
-	return http.ListenAndServe(addr, engine)
+	server := &http.Server{Addr: addr, Handler: engine}
+	engine.setServer(server)
+	return server.ListenAndServe()
 }
 
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-
-	var middlewares []HandlerFunc
-	for _, group := range engine.groups {
-		prefix := group.prefix + "/"
-		if strings.HasPrefix(req.URL.Path, prefix) {
-			middlewares = append(middlewares, group.middlewares...)
-		}
-	}
-
 	context := NewContext(w, req)
-	context.handlers = middlewares
 	context.engine = engine
-	engine.router.handle(context)
+	if engine.runRequestHooks(context) { // OnRequest 钩子可能修改了路径或拒绝了请求
+		engine.route(context)
+	}
+	engine.runResponseHooks(context)
+}
+
+// route 根据 Host 和路径选出路由树和分组中间件，然后交给路由处理
+func (engine *Engine) route(context *Context) {
+	router, hostParams := engine.matchHost(context.Req.Host) // 先根据 Host 选出路由树，未匹配上时使用默认路由树
+
+	context.Params = hostParams
+	context.handlers = engine.groupMiddlewares(router, context.Path, "") // 版本分组的中间件在协商出版本后再加入
+	router.handle(context)
 }
 
 // SetFuncMap 设置渲染函数，可以在模板中指定，某个数据使用某个渲染函数
@@ -56,44 +88,110 @@ func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
 
 // LoadHTMLGlob 指定模板的路径，将模板加载到内存中
 func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmlTemplates = template.New("")
-	engine.htmlTemplates.Funcs(engine.funcMap)
-	engine.htmlTemplates = template.Must(engine.htmlTemplates.ParseGlob(pattern))
+	base := template.New("")
+	base.Funcs(engine.builtinFuncMap()) // 内置渲染函数，例如 url、t
+	base.Funcs(engine.funcMap)          // 自定义的渲染函数可以覆盖内置的同名函数
+	base = template.Must(base.ParseGlob(pattern))
+
+	// 执行过的模板不能再克隆，因此保留一份从未执行过的模板，供不同语言克隆
+	engine.templateMu.Lock()
+	engine.htmlBase = base
+	engine.localizedTemplates = nil
+	engine.templateMu.Unlock()
+	engine.htmlTemplates = template.Must(base.Clone())
+}
+
+// builtinFuncMap 内置的模板渲染函数
+func (engine *Engine) builtinFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"url": engine.urlFunc,
+		"t": func(key string, args ...interface{}) string { // 未设置翻译器时原样输出，设置后替换为对应语言的翻译函数
+			return untranslated(key, args)
+		},
+	}
 }
 
 type RouterGroup struct {
 	prefix      string
 	middlewares []HandlerFunc
 	engine      *Engine
+	router      *router       // 分组注册路由时使用的路由树，Host 分组有自己独立的路由树
+	noRoute     []HandlerFunc // 分组下未匹配任何路由时的处理函数
+	version     *apiVersion   // 版本分组的版本，子分组继承
 }
 
 func (group *RouterGroup) Group(prefix string) *RouterGroup {
 	newGroup := &RouterGroup{
-		prefix: group.prefix + prefix,
-		engine: group.engine,
+		prefix:  group.prefix + prefix,
+		engine:  group.engine,
+		router:  group.router,
+		version: group.version,
 	}
 	group.engine.groups = append(group.engine.groups, newGroup)
 	return newGroup
 }
 
-func (group *RouterGroup) addRoute(method string, pattern string, handler HandlerFunc) {
+func (group *RouterGroup) addRoute(method string, pattern string, handler HandlerFunc, opts ...RouteOption) {
 	pattern = group.prefix + pattern
-	group.engine.router.addRoute(method, pattern, handler)
+	version := ""
+	if group.version != nil {
+		version = group.version.name
+	}
+	group.router.addRoute(method, pattern, version, handler, opts...)
 }
 
-func (group *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
+// anyMethods Any 注册的请求方法
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+	http.MethodTrace,
 }
 
-func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	group.addRoute("POST", pattern, handler)
+// Handle 注册任意请求方法的路由
+func (group *RouterGroup) Handle(method string, pattern string, handler HandlerFunc, opts ...RouteOption) {
+	group.addRoute(method, pattern, handler, opts...)
+}
+
+func (group *RouterGroup) GET(pattern string, handler HandlerFunc, opts ...RouteOption) {
+	group.addRoute("GET", pattern, handler, opts...)
+}
+
+func (group *RouterGroup) POST(pattern string, handler HandlerFunc, opts ...RouteOption) {
+	group.addRoute("POST", pattern, handler, opts...)
+}
+
+func (group *RouterGroup) PUT(pattern string, handler HandlerFunc, opts ...RouteOption) {
+	group.addRoute("PUT", pattern, handler, opts...)
+}
+
+func (group *RouterGroup) PATCH(pattern string, handler HandlerFunc, opts ...RouteOption) {
+	group.addRoute("PATCH", pattern, handler, opts...)
+}
+
+func (group *RouterGroup) DELETE(pattern string, handler HandlerFunc, opts ...RouteOption) {
+	group.addRoute("DELETE", pattern, handler, opts...)
+}
+
+func (group *RouterGroup) HEAD(pattern string, handler HandlerFunc, opts ...RouteOption) {
+	group.addRoute("HEAD", pattern, handler, opts...)
+}
+
+func (group *RouterGroup) OPTIONS(pattern string, handler HandlerFunc, opts ...RouteOption) {
+	group.addRoute("OPTIONS", pattern, handler, opts...)
+}
+
+// Any 为所有常用的请求方法注册同一个路由
+func (group *RouterGroup) Any(pattern string, handler HandlerFunc, opts ...RouteOption) {
+	for _, method := range anyMethods {
+		group.addRoute(method, pattern, handler, opts...)
+	}
 }
 
 func (group *RouterGroup) Use(handlerFunc ...HandlerFunc) {
 	group.middlewares = append(group.middlewares, handlerFunc...)
 }
 
-//Static 例如 r.Static("/assets", "./static")
+// Static 例如 r.Static("/assets", "./static")
 func (group *RouterGroup) Static(relativePath string, root string) {
 	urlPattern := path.Join(relativePath, "/*filepath")                // 1.拼接路径，例如得到 "/assets/*filepath"
 	handler := group.createStaticHandler(relativePath, http.Dir(root)) // 2.得到路由处理函数 handler
@@ -0,0 +1,159 @@
+// Package geetest 提供在进程内测试 gee 应用的工具：流式构造请求，并对响应做断言。
+//
+//	geetest.New(engine).
+//		GET("/user/1").
+//		WithHeader("Authorization", "Bearer token").
+//		Expect().
+//		AssertStatus(t, http.StatusOK).
+//		AssertJSON(t, "data.name", "Tom")
+package geetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"geeweb/gee"
+)
+
+// Client 测试客户端，请求直接交给 handler 处理，不经过网络
+type Client struct {
+	handler http.Handler
+}
+
+// New 创建测试客户端，handler 通常是 *gee.Engine
+func New(handler http.Handler) *Client {
+	return &Client{handler: handler}
+}
+
+// Request 待发送的请求，通过 With 系列方法设置请求内容，调用 Expect 发送请求
+type Request struct {
+	client  *Client
+	method  string
+	path    string
+	query   url.Values
+	header  http.Header
+	cookies []*http.Cookie
+	body    io.Reader
+	host    string
+}
+
+func (cl *Client) Request(method string, path string) *Request {
+	return &Request{
+		client: cl,
+		method: method,
+		path:   path,
+		query:  make(url.Values),
+		header: make(http.Header),
+	}
+}
+
+func (cl *Client) GET(path string) *Request {
+	return cl.Request(http.MethodGet, path)
+}
+
+func (cl *Client) POST(path string) *Request {
+	return cl.Request(http.MethodPost, path)
+}
+
+func (cl *Client) PUT(path string) *Request {
+	return cl.Request(http.MethodPut, path)
+}
+
+func (cl *Client) PATCH(path string) *Request {
+	return cl.Request(http.MethodPatch, path)
+}
+
+func (cl *Client) DELETE(path string) *Request {
+	return cl.Request(http.MethodDelete, path)
+}
+
+func (cl *Client) HEAD(path string) *Request {
+	return cl.Request(http.MethodHead, path)
+}
+
+func (r *Request) WithHeader(key string, value string) *Request {
+	r.header.Add(key, value)
+	return r
+}
+
+func (r *Request) WithQuery(key string, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+func (r *Request) WithCookie(cookie *http.Cookie) *Request {
+	r.cookies = append(r.cookies, cookie)
+	return r
+}
+
+// WithHost 设置请求的 Host，用于测试按 Host 路由的分组
+func (r *Request) WithHost(host string) *Request {
+	r.host = host
+	return r
+}
+
+func (r *Request) WithBody(body []byte) *Request {
+	r.body = bytes.NewReader(body)
+	return r
+}
+
+// WithJSON 将 v 编码为 JSON 作为请求体，并设置 Content-Type。编码失败说明测试代码有误，直接 panic
+func (r *Request) WithJSON(v interface{}) *Request {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("geetest: marshal json: %v", err))
+	}
+	r.header.Set("Content-Type", "application/json")
+	return r.WithBody(data)
+}
+
+// WithForm 将 form 编码后作为请求体，并设置 Content-Type
+func (r *Request) WithForm(form url.Values) *Request {
+	r.header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.body = strings.NewReader(form.Encode())
+	return r
+}
+
+// Expect 发送请求，返回响应
+func (r *Request) Expect() *Response {
+	target := r.path
+	if len(r.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+
+	req := httptest.NewRequest(r.method, target, r.body)
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	if r.host != "" {
+		req.Host = r.host
+	}
+
+	w := httptest.NewRecorder()
+	r.client.handler.ServeHTTP(w, req)
+	return FromRecorder(w)
+}
+
+// CreateTestContext 创建一个不经过路由的 gee.Context，用于单独测试中间件。
+// req 为 nil 时使用 GET /，handlers 依次执行，调用 c.Next() 开始执行，响应写入返回的 recorder 中。
+func CreateTestContext(req *http.Request, handlers ...gee.HandlerFunc) (*gee.Context, *httptest.ResponseRecorder) {
+	if req == nil {
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+	}
+	w := httptest.NewRecorder()
+	c, _ := gee.CreateTestContext(w, req, handlers...)
+	return c, w
+}
@@ -0,0 +1,66 @@
+package geetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"geeweb/gee"
+)
+
+func TestClient(t *testing.T) {
+	r := gee.New()
+	r.POST("/echo/:id", func(c *gee.Context) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(c.Req.Body).Decode(&body); err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		http.SetCookie(c.Writer, &http.Cookie{Name: "session", Value: "abc"})
+		c.SetHeader("X-Token", c.Req.Header.Get("X-Token"))
+		c.JSON(http.StatusOK, gee.H{"id": c.Param("id"), "q": c.Query("q"), "body": body})
+	})
+
+	New(r).POST("/echo/7").
+		WithHeader("X-Token", "t1").
+		WithQuery("q", "go").
+		WithJSON(gee.H{"tags": []string{"a", "b"}, "n": 1}).
+		Expect().
+		AssertStatus(t, http.StatusOK).
+		AssertHeader(t, "X-Token", "t1").
+		AssertHeader(t, "Content-Type", "application/json").
+		AssertJSON(t, "id", "7").
+		AssertJSON(t, "q", "go").
+		AssertJSON(t, "body.tags.1", "b").
+		AssertJSON(t, "body.n", 1).
+		AssertCookie(t, "session", "abc")
+}
+
+func TestHTML(t *testing.T) {
+	r := gee.New()
+	r.GET("/", func(c *gee.Context) {
+		c.SetHeader("Content-Type", "text/html; charset=utf-8")
+		c.Data(http.StatusOK, []byte("<p>hello</p>"))
+	})
+	client := New(r)
+	client.GET("/").Expect().AssertStatus(t, http.StatusOK).AssertHTML(t, "<p>hello</p>")
+	client.GET("/missing").Expect().AssertContains(t, "404 NOT FOUND")
+}
+
+func TestCreateTestContext(t *testing.T) {
+	auth := func(c *gee.Context) {
+		if c.Req.Header.Get("Authorization") == "" {
+			c.Fail(http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		c.Next()
+	}
+
+	c, w := CreateTestContext(nil, auth, func(c *gee.Context) {
+		t.Fatal("handler should not be called")
+	})
+	c.Next()
+	FromRecorder(w).
+		AssertStatus(t, http.StatusUnauthorized).
+		AssertJSON(t, "message", "unauthorized")
+}
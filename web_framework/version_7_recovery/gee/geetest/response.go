@@ -0,0 +1,157 @@
+package geetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Response 请求的响应，Assert 系列方法断言失败时调用 t.Fatalf，并返回 Response 本身以便链式调用
+type Response struct {
+	Code   int
+	Header http.Header
+	Body   []byte
+
+	cookies []*http.Cookie
+}
+
+// FromRecorder 将 httptest.ResponseRecorder 包装为 Response，便于对 CreateTestContext 的结果做断言
+func FromRecorder(w *httptest.ResponseRecorder) *Response {
+	result := w.Result()
+	return &Response{
+		Code:    w.Code,
+		Header:  w.Header(),
+		Body:    w.Body.Bytes(),
+		cookies: result.Cookies(),
+	}
+}
+
+func (r *Response) String() string {
+	return string(r.Body)
+}
+
+// Cookie 按名称获取响应设置的 cookie，不存在时返回 nil
+func (r *Response) Cookie(name string) *http.Cookie {
+	for _, cookie := range r.cookies {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// JSONPath 解析 JSON 响应体，按路径取值。路径以 "." 分隔，数组使用下标，例如 "data.items.0.name"，空路径返回整个响应体
+func (r *Response) JSONPath(path string) (interface{}, error) {
+	var cur interface{}
+	decoder := json.NewDecoder(bytes.NewReader(r.Body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&cur); err != nil {
+		return nil, fmt.Errorf("geetest: decode json: %v", err)
+	}
+	if path == "" {
+		return cur, nil
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]interface{}:
+			value, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("geetest: json path %q: key %q not found", path, key)
+			}
+			cur = value
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("geetest: json path %q: invalid index %q", path, key)
+			}
+			cur = v[i]
+		default:
+			return nil, fmt.Errorf("geetest: json path %q: cannot index %T with %q", path, cur, key)
+		}
+	}
+	return cur, nil
+}
+
+func (r *Response) AssertStatus(t testing.TB, code int) *Response {
+	t.Helper()
+	if r.Code != code {
+		t.Fatalf("expect status %d, got %d, body: %s", code, r.Code, r.Body)
+	}
+	return r
+}
+
+func (r *Response) AssertHeader(t testing.TB, key string, value string) *Response {
+	t.Helper()
+	if got := r.Header.Get(key); got != value {
+		t.Fatalf("expect header %s: %q, got %q", key, value, got)
+	}
+	return r
+}
+
+// AssertJSON 断言 JSON 响应体中 path 处的值等于 want，want 会先经过 JSON 编解码，因此结构体与 map、int 与 float64 可以互相比较
+func (r *Response) AssertJSON(t testing.TB, path string, want interface{}) *Response {
+	t.Helper()
+	got, err := r.JSONPath(path)
+	if err != nil {
+		t.Fatalf("%v, body: %s", err, r.Body)
+	}
+	normalized, err := normalizeJSON(want)
+	if err != nil {
+		t.Fatalf("geetest: %v", err)
+	}
+	if !reflect.DeepEqual(got, normalized) {
+		t.Fatalf("expect json %q to be %v, got %v", path, want, got)
+	}
+	return r
+}
+
+// AssertHTML 断言响应是 HTML，并且响应体中包含 snippet
+func (r *Response) AssertHTML(t testing.TB, snippet string) *Response {
+	t.Helper()
+	if contentType := r.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/html") {
+		t.Fatalf("expect html response, got Content-Type %q", contentType)
+	}
+	return r.AssertContains(t, snippet)
+}
+
+func (r *Response) AssertContains(t testing.TB, snippet string) *Response {
+	t.Helper()
+	if !bytes.Contains(r.Body, []byte(snippet)) {
+		t.Fatalf("expect body to contain %q, got: %s", snippet, r.Body)
+	}
+	return r
+}
+
+func (r *Response) AssertCookie(t testing.TB, name string, value string) *Response {
+	t.Helper()
+	cookie := r.Cookie(name)
+	if cookie == nil {
+		t.Fatalf("expect cookie %q to be set", name)
+	}
+	if cookie.Value != value {
+		t.Fatalf("expect cookie %s=%q, got %q", name, value, cookie.Value)
+	}
+	return r
+}
+
+// normalizeJSON 将 v 经过 JSON 编解码，得到与 JSONPath 相同表示形式的值
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,221 @@
+//go:build linux
+// +build linux
+
+package gee
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// listenerFDEnv 子进程通过该环境变量得知继承的监听套接字的文件描述符
+const listenerFDEnv = "GEE_LISTENER_FD"
+
+// readyFDEnv 子进程通过该环境变量得知通知父进程已就绪的管道的文件描述符
+const readyFDEnv = "GEE_READY_FD"
+
+// RunGraceful 启动服务并支持不中断连接的重启：
+//   - 收到 SIGHUP 或 SIGUSR2 时，重新执行当前的二进制文件（部署时已被替换为新版本），通过 ExtraFiles 和环境变量
+//     将监听套接字交给新进程，新进程直接在同一个套接字上接受连接。旧进程等到新进程通过管道通知已就绪后，
+//     才停止接受连接，处理完已有请求后退出；新进程退出或 GracefulRestartTimeout 内没有就绪时，旧进程继续提供服务；
+//   - 收到 SIGINT 或 SIGTERM 时，直接优雅关闭。
+//
+// 两种情况下都会执行 RegisterOnShutdown 注册的回调，最多等待 GracefulShutdownTimeout，正常关闭时返回 nil。
+// 直接关闭时先执行回调并等待 ShutdownDrainDelay，期间继续接受连接，之后才停止监听。
+func (engine *Engine) RunGraceful(addr string) error {
+	ln, err := gracefulListener(addr)
+	if err != nil {
+		return err
+	}
+	tracker := &newConnTracker{conns: make(map[net.Conn]struct{})}
+	server := &http.Server{Addr: addr, Handler: engine, ConnState: tracker.track}
+	engine.setServer(server)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGUSR2, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(ln)
+	}()
+	notifyReady()
+
+	for {
+		select {
+		case err := <-served:
+			return err
+		case sig := <-sigs:
+			handoff := sig == syscall.SIGHUP || sig == syscall.SIGUSR2
+			if handoff {
+				pid, err := forkWithListener(ln, GracefulRestartTimeout)
+				if err != nil { // 新进程启动失败或没有就绪时继续提供服务
+					log.Printf("gee: graceful restart failed: %v", err)
+					continue
+				}
+				log.Printf("gee: handed listener %s over to process %d", ln.Addr(), pid)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), GracefulShutdownTimeout)
+			defer cancel()
+			if !handoff {
+				// 先执行回调让就绪检查失败，并在 ShutdownDrainDelay 内继续接受连接，等负载均衡不再转发新请求
+				engine.beginShutdown(ctx, true)
+			}
+
+			// 停止接受连接。Shutdown 会直接关闭开始关闭后才读到第一个请求的连接，
+			// 因此等已接受的新连接读到请求后再调用 Shutdown 等待请求处理完成
+			ln.Close()
+			<-served
+			tracker.wait(ctx, newConnWait)
+			if handoff { // 新进程已经在同一个套接字上接受连接，不需要等待
+				engine.beginShutdown(ctx, false)
+			}
+			return server.Shutdown(ctx)
+		}
+	}
+}
+
+// newConnWait 关闭前等待新连接发送第一个请求的最长时间，与 http.Server 将新连接视为空闲连接的时间一致
+const newConnWait = 5 * time.Second
+
+// newConnTracker 记录已接受但还没有读到请求的连接
+type newConnTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+func (t *newConnTracker) track(conn net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if state == http.StateNew {
+		t.conns[conn] = struct{}{}
+	} else {
+		delete(t.conns, conn)
+	}
+}
+
+// wait 等待所有新连接读到请求，最多等待 timeout
+func (t *newConnTracker) wait(ctx context.Context, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		t.mu.Lock()
+		n := len(t.conns)
+		t.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// gracefulListener 由 RunGraceful 重启的进程继承父进程的监听套接字，否则监听 addr
+func gracefulListener(addr string) (net.Listener, error) {
+	value := os.Getenv(listenerFDEnv)
+	if value == "" {
+		return net.Listen("tcp", addr)
+	}
+	os.Unsetenv(listenerFDEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("gee: invalid %s %q", listenerFDEnv, value)
+	}
+	f := os.NewFile(uintptr(fd), "gee-listener")
+	defer f.Close() // FileListener 复制了文件描述符，原来的可以关闭
+	return net.FileListener(f)
+}
+
+// notifyReady 由 RunGraceful 重启的进程开始接受连接后，通知父进程可以停止接受连接
+func notifyReady() {
+	value := os.Getenv(readyFDEnv)
+	if value == "" {
+		return
+	}
+	os.Unsetenv(readyFDEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("gee: invalid %s %q", readyFDEnv, value)
+		return
+	}
+	f := os.NewFile(uintptr(fd), "gee-ready")
+	defer f.Close()
+	if _, err := f.Write([]byte{1}); err != nil {
+		log.Printf("gee: notify parent process: %v", err)
+	}
+}
+
+// forkWithListener 以相同的参数启动当前的二进制文件，监听套接字作为文件描述符 3，通知就绪的管道作为文件描述符 4。
+// 新进程在 timeout 内就绪时返回它的进程号，否则结束新进程并返回错误
+func forkWithListener(ln net.Listener, timeout time.Duration) (int, error) {
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return 0, fmt.Errorf("listener %T does not support SyscallConn", ln)
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	// 二进制文件被替换后 /proc/self/exe 指向已删除的旧文件，os.Executable 会返回原来的路径，即新版本
+	path, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	ready, notify, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer ready.Close()
+
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, listenerFDEnv+"=") && !strings.HasPrefix(kv, readyFDEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, listenerFDEnv+"=3", readyFDEnv+"=4")
+
+	// 不使用 exec.Cmd 的 ExtraFiles：它通过 os.File.Fd 获取文件描述符，会把与监听套接字共享的文件状态改为阻塞模式，
+	// 之后本进程阻塞在 accept 中，关闭监听套接字时会一直等待
+	var pid int
+	var forkErr error
+	err = raw.Control(func(fd uintptr) {
+		pid, forkErr = syscall.ForkExec(path, append([]string{path}, os.Args[1:]...), &syscall.ProcAttr{
+			Env:   env,
+			Files: []uintptr{0, 1, 2, fd, notify.Fd()},
+		})
+	})
+	notify.Close() // 只保留子进程中的写端，子进程退出时读端返回 EOF
+	if err == nil {
+		err = forkErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	ready.SetReadDeadline(time.Now().Add(timeout))
+	if _, err := ready.Read(make([]byte, 1)); err != nil {
+		if process, findErr := os.FindProcess(pid); findErr == nil {
+			process.Kill()
+			go process.Wait()
+		}
+		if err == io.EOF {
+			return 0, fmt.Errorf("process %d exited before it was ready", pid)
+		}
+		return 0, fmt.Errorf("process %d not ready: %v", pid, err)
+	}
+	return pid, nil
+}
@@ -0,0 +1,198 @@
+//go:build linux
+// +build linux
+
+package gee
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startGracefulHelper 编译并启动 testdata/graceful，返回进程、请求函数以及进程号
+func startGracefulHelper(t *testing.T, env ...string) (*exec.Cmd, func(path string) (string, error), string) {
+	bin := filepath.Join(t.TempDir(), "graceful")
+	if out, err := exec.Command("go", "build", "-o", bin, "./testdata/graceful").CombinedOutput(); err != nil {
+		t.Fatalf("build helper: %v\n%s", err, out)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	parent := exec.Command(bin, addr)
+	parent.Env = append(os.Environ(), env...)
+	parent.Stderr = os.Stderr
+	if err := parent.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: 5 * time.Second}
+	get := func(path string) (string, error) {
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	var pid string
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if pid, err = get("/pid"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			parent.Process.Kill()
+			t.Fatalf("helper did not start: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if pid != strconv.Itoa(parent.Process.Pid) {
+		parent.Process.Kill()
+		t.Fatalf("unexpected pid %s", pid)
+	}
+	return parent, get, pid
+}
+
+func TestRunGracefulRestart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a helper binary")
+	}
+	parent, get, pid := startGracefulHelper(t)
+	defer parent.Process.Kill()
+
+	// 重启前发出的慢请求由旧进程处理完成
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		body, err := get("/slow")
+		if err != nil || body != pid {
+			t.Errorf("in-flight request: got %q, %v", body, err)
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := parent.Process.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重启过程中的请求都不会失败，最终由新进程处理
+	var child string
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		body, err := get("/pid")
+		if err != nil {
+			t.Fatalf("request failed during restart: %v", err)
+		}
+		if body != pid {
+			child = body
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("child process did not take over")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	wg.Wait()
+
+	childPid, _ := strconv.Atoi(child)
+	defer syscall.Kill(childPid, syscall.SIGKILL)
+	if err := parent.Wait(); err != nil {
+		t.Fatalf("old process should exit cleanly: %v", err)
+	}
+
+	// 新进程收到 SIGTERM 后优雅退出，不再接受连接
+	if err := syscall.Kill(childPid, syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if _, err := get("/pid"); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("child process did not shut down")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRunGracefulRestartNotReady(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a helper binary")
+	}
+	parent, get, pid := startGracefulHelper(t, "GRACEFUL_FAIL_RESTART=1")
+	defer parent.Process.Kill()
+
+	exited := make(chan error, 1)
+	go func() { exited <- parent.Wait() }()
+	if err := parent.Process.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	// 新进程没有就绪就退出了，旧进程继续提供服务
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		select {
+		case err := <-exited:
+			t.Fatalf("old process exited although the new one was not ready: %v", err)
+		default:
+		}
+		body, err := get("/pid")
+		if err != nil || body != pid {
+			t.Fatalf("expected old process %s to keep serving, got %q, %v", pid, body, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestRunGracefulDrainDelay(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a helper binary")
+	}
+	parent, get, _ := startGracefulHelper(t, "GRACEFUL_DRAIN_DELAY=1s")
+	defer parent.Process.Kill()
+
+	if body, err := get("/readyz"); err != nil || !strings.Contains(body, `"status":"ok"`) {
+		t.Fatalf("expected ready before shutdown, got %q, %v", body, err)
+	}
+	start := time.Now()
+	if err := parent.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	// 收到 SIGTERM 后就绪检查立即失败，但在 ShutdownDrainDelay 内仍然接受连接
+	for {
+		body, err := get("/readyz")
+		if err != nil {
+			t.Fatalf("server should keep serving during the drain delay: %v", err)
+		}
+		if strings.Contains(body, `"status":"fail"`) {
+			break
+		}
+		if time.Since(start) > 500*time.Millisecond {
+			t.Fatalf("readiness should fail right after SIGTERM, got %q", body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := get("/pid"); err != nil {
+		t.Fatalf("server should keep serving during the drain delay: %v", err)
+	}
+	if err := parent.Wait(); err != nil {
+		t.Fatalf("process should exit cleanly: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("process exited after %v, before the drain delay", elapsed)
+	}
+}
@@ -0,0 +1,38 @@
+//go:build !linux
+// +build !linux
+
+package gee
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+)
+
+// RunGraceful 在非 Linux 平台上不支持移交监听套接字，只在收到中断信号时优雅关闭，
+// 最多等待 GracefulShutdownTimeout，正常关闭时返回 nil
+func (engine *Engine) RunGraceful(addr string) error {
+	server := &http.Server{Addr: addr, Handler: engine}
+	engine.setServer(server)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-sigs:
+		ctx, cancel := context.WithTimeout(context.Background(), GracefulShutdownTimeout)
+		defer cancel()
+		err := engine.Shutdown(ctx)
+		<-served
+		return err
+	}
+}
@@ -0,0 +1,225 @@
+// Package health 提供存活（liveness）和就绪（readiness）检查接口，一行代码即可挂载到 gee.Engine：
+//
+//	h := health.New()
+//	h.AddReadinessCheck("db", health.CheckerFunc(db.PingContext), health.WithTimeout(time.Second))
+//	h.Register(r) // 注册 /livez、/readyz 和 /healthz
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"geeweb/gee"
+)
+
+// DefaultTimeout 未通过 WithTimeout 设置时，单个检查的超时时间
+const DefaultTimeout = 5 * time.Second
+
+// Checker 健康检查，返回 nil 表示健康，需要在 ctx 超时后尽快返回
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc 将函数转换为 Checker
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// CheckOption 检查的可选配置
+type CheckOption func(c *check)
+
+// WithTimeout 设置检查的超时时间，超时视为失败
+func WithTimeout(timeout time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+// WithCacheTTL 在 ttl 内复用上一次的检查结果，避免探针频繁访问数据库等依赖
+func WithCacheTTL(ttl time.Duration) CheckOption {
+	return func(c *check) {
+		c.cacheTTL = ttl
+	}
+}
+
+type check struct {
+	name     string
+	checker  Checker
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	last      CheckResult
+	checkedAt time.Time
+}
+
+// CheckResult 单个检查的结果
+type CheckResult struct {
+	Name    string  `json:"name"`
+	Status  string  `json:"status"` // "ok" 或 "fail"
+	Latency float64 `json:"latency_ms"`
+	Error   string  `json:"error,omitempty"`
+	Cached  bool    `json:"cached,omitempty"`
+}
+
+// Report 一组检查的汇总结果，所有检查都成功时 Status 为 "ok"
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Health 保存存活检查和就绪检查。存活检查失败说明进程需要重启，就绪检查失败说明暂时不能接收流量
+type Health struct {
+	mu           sync.Mutex
+	liveness     []*check
+	readiness    []*check
+	shuttingDown bool
+}
+
+func New() *Health {
+	return &Health{}
+}
+
+// AddLivenessCheck 添加存活检查，存活检查同时也是就绪检查的一部分
+func (h *Health) AddLivenessCheck(name string, checker Checker, opts ...CheckOption) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness = append(h.liveness, newCheck(name, checker, opts))
+}
+
+// AddReadinessCheck 添加就绪检查，例如数据库、缓存等依赖是否可用
+func (h *Health) AddReadinessCheck(name string, checker Checker, opts ...CheckOption) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness = append(h.readiness, newCheck(name, checker, opts))
+}
+
+func newCheck(name string, checker Checker, opts []CheckOption) *check {
+	c := &check{name: name, checker: checker, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// SetShuttingDown 标记服务正在关闭，之后就绪检查始终失败。Register 会在 Engine.Shutdown 开始时自动调用
+func (h *Health) SetShuttingDown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.shuttingDown = true
+}
+
+// Liveness 执行存活检查
+func (h *Health) Liveness(ctx context.Context) Report {
+	h.mu.Lock()
+	checks := append([]*check{}, h.liveness...)
+	h.mu.Unlock()
+	return run(ctx, checks)
+}
+
+// Readiness 执行存活检查和就绪检查，服务正在关闭时直接失败
+func (h *Health) Readiness(ctx context.Context) Report {
+	h.mu.Lock()
+	checks := append(append([]*check{}, h.liveness...), h.readiness...)
+	shuttingDown := h.shuttingDown
+	h.mu.Unlock()
+
+	report := run(ctx, checks)
+	if shuttingDown {
+		report.Status = "fail"
+		report.Checks = append(report.Checks, CheckResult{Name: "shutdown", Status: "fail", Error: "server is shutting down"})
+	}
+	return report
+}
+
+// run 并发执行所有检查，结果的顺序与注册顺序一致
+func run(ctx context.Context, checks []*check) Report {
+	report := Report{Status: "ok", Checks: make([]CheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != "ok" {
+			report.Status = "fail"
+		}
+	}
+	return report
+}
+
+func (c *check) run(ctx context.Context) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock() // 同一个检查同时只执行一次，并发的探针等待并复用结果
+	if c.cacheTTL > 0 && !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.cacheTTL {
+		result := c.last
+		result.Cached = true
+		return result
+	}
+
+	start := time.Now()
+	err := c.checkWithTimeout(ctx)
+	result := CheckResult{
+		Name:    c.name,
+		Status:  "ok",
+		Latency: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status, result.Error = "fail", err.Error()
+	}
+	c.last, c.checkedAt = result, time.Now()
+	return result
+}
+
+// checkWithTimeout 执行检查，Checker 没有响应 ctx 取消时也能按时返回
+func (c *check) checkWithTimeout(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				done <- errors.New("check panicked")
+			}
+		}()
+		done <- c.checker.Check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Register 在 engine 上注册 /livez（存活检查）、/readyz（就绪检查）和 /healthz（等同于 /readyz），
+// 检查成功返回 200，失败返回 503，响应体为 JSON 格式的 Report。同时在 engine.Shutdown 开始时让就绪检查失败。
+func (h *Health) Register(engine *gee.Engine) {
+	engine.RegisterOnShutdown(h.SetShuttingDown)
+	engine.GET("/livez", h.handler(h.Liveness))
+	engine.GET("/readyz", h.handler(h.Readiness))
+	engine.GET("/healthz", h.handler(h.Readiness))
+}
+
+func (h *Health) handler(probe func(ctx context.Context) Report) gee.HandlerFunc {
+	return func(c *gee.Context) {
+		report := probe(c.Req.Context())
+		code := http.StatusOK
+		if report.Status != "ok" {
+			code = http.StatusServiceUnavailable
+		}
+		c.SetHeader("Cache-Control", "no-store")
+		c.JSON(code, report)
+	}
+}
@@ -0,0 +1,89 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"geeweb/gee"
+)
+
+func TestHealth(t *testing.T) {
+	var dbErr atomic.Value
+	dbErr.Store("")
+	var calls int32
+
+	h := New()
+	h.AddLivenessCheck("goroutines", CheckerFunc(func(ctx context.Context) error { return nil }))
+	h.AddReadinessCheck("db", CheckerFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		if msg := dbErr.Load().(string); msg != "" {
+			return errors.New(msg)
+		}
+		return nil
+	}))
+	h.AddReadinessCheck("slow", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second) // 不响应 ctx 取消，依然按超时返回
+		return nil
+	}), WithTimeout(10*time.Millisecond), WithCacheTTL(time.Minute))
+
+	r := gee.New()
+	h.Register(r)
+
+	do := func(path string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+
+	if code := do("/livez"); code != http.StatusOK {
+		t.Fatalf("liveness: expect 200, got %d", code)
+	}
+	if code := do("/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("readiness: slow check should time out, got %d", code)
+	}
+
+	report := h.Readiness(context.Background())
+	if report.Checks[2].Name != "slow" || !report.Checks[2].Cached || report.Checks[2].Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("expect cached timeout result, got %+v", report.Checks[2])
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("db check without cache should run every time, got %d", calls)
+	}
+
+	dbErr.Store("connection refused")
+	report = h.Readiness(context.Background())
+	if report.Status != "fail" || report.Checks[1].Error != "connection refused" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestReadinessFailsOnShutdown(t *testing.T) {
+	h := New()
+	r := gee.New()
+	h.Register(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expect 200, got %d", w.Code)
+	}
+
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("readiness should fail after shutdown starts, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("liveness should not be affected by shutdown, got %d", w.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package gee
+
+// Hook 请求生命周期中的钩子函数，与中间件不同，钩子注册在 Engine 上，对所有请求生效，不占用分组的中间件位置
+type Hook func(c *Context)
+
+// ErrorHook 处理函数 panic 被 Recovery 捕获后执行的钩子函数，err 为 recover() 的返回值
+type ErrorHook func(c *Context, err interface{})
+
+// OnRequest 注册路由之前执行的钩子。可以修改 c.Req.URL.Path（或 c.Path）、c.Req.Method、c.Req.Host 改变路由结果，
+// 也可以调用 c.Abort 或写入响应提前拒绝请求，此时不再路由，也不执行任何中间件
+func (engine *Engine) OnRequest(hooks ...Hook) {
+	engine.onRequest = append(engine.onRequest, hooks...)
+}
+
+// OnRouteMatched 注册匹配到路由之后、执行中间件之前的钩子，此时 c.FullPath() 和 c.Param 可用，
+// 调用 c.Abort 会跳过中间件和处理函数。未匹配任何路由的请求不会执行
+func (engine *Engine) OnRouteMatched(hooks ...Hook) {
+	engine.onRouteMatched = append(engine.onRouteMatched, hooks...)
+}
+
+// OnResponse 注册请求处理完毕后执行的钩子，此时可以通过 c.ResponseStatus() 和 c.ResponseSize() 获取最终的状态码和大小。
+// 处理过程中发生未被 Recovery 捕获的 panic 时不会执行
+func (engine *Engine) OnResponse(hooks ...Hook) {
+	engine.onResponse = append(engine.onResponse, hooks...)
+}
+
+// OnError 注册 Recovery 捕获到 panic 后执行的钩子，在 RecoveryFunc 之前执行，客户端断开连接导致的 panic 也会执行
+func (engine *Engine) OnError(hooks ...ErrorHook) {
+	engine.onError = append(engine.onError, hooks...)
+}
+
+// runRequestHooks 执行 OnRequest 钩子，返回 false 表示请求已被拒绝
+func (engine *Engine) runRequestHooks(c *Context) bool {
+	if len(engine.onRequest) == 0 {
+		return true
+	}
+	path, method := c.Path, c.Method
+	for _, hook := range engine.onRequest {
+		hook(c)
+		if c.IsAborted() || c.Written() {
+			return false
+		}
+	}
+	// 钩子可以修改 c.Path 或 c.Req.URL.Path，以修改过的为准，并保持两者一致
+	if c.Path != path {
+		c.Req.URL.Path = c.Path
+	} else {
+		c.Path = c.Req.URL.Path
+	}
+	if c.Method != method {
+		c.Req.Method = c.Method
+	} else {
+		c.Method = c.Req.Method
+	}
+	return true
+}
+
+func (engine *Engine) runRouteMatchedHooks(c *Context) {
+	for _, hook := range engine.onRouteMatched {
+		hook(c)
+		if c.IsAborted() {
+			return
+		}
+	}
+}
+
+func (engine *Engine) runResponseHooks(c *Context) {
+	for _, hook := range engine.onResponse {
+		hook(c)
+	}
+}
+
+func runErrorHooks(c *Context, err interface{}) {
+	if c.engine == nil {
+		return
+	}
+	for _, hook := range c.engine.onError {
+		hook(c, err)
+	}
+}
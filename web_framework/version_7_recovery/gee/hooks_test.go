@@ -0,0 +1,102 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	var events []string
+	r := New()
+	r.OnRequest(func(c *Context) {
+		events = append(events, "request "+c.Path)
+		if strings.HasPrefix(c.Req.URL.Path, "/old/") { // 重写路径后再路由
+			c.Req.URL.Path = "/new/" + strings.TrimPrefix(c.Req.URL.Path, "/old/")
+		}
+		if c.Req.Header.Get("X-Blocked") != "" {
+			c.Fail(http.StatusForbidden, "blocked")
+		}
+	})
+	r.OnRouteMatched(func(c *Context) {
+		events = append(events, "matched "+c.FullPath()+" "+c.Param("id"))
+	})
+	r.OnResponse(func(c *Context) {
+		events = append(events, "response "+http.StatusText(c.ResponseStatus()))
+	})
+
+	api := r.Group("/new")
+	api.Use(func(c *Context) {
+		events = append(events, "middleware")
+	})
+	api.GET("/:id", func(c *Context) {
+		c.String(http.StatusOK, "item %s", c.Param("id"))
+	})
+
+	cases := []struct {
+		path    string
+		blocked bool
+		code    int
+		events  []string
+	}{
+		{"/old/1", false, http.StatusOK, []string{"request /old/1", "matched /new/:id 1", "middleware", "response OK"}},
+		{"/missing", false, http.StatusNotFound, []string{"request /missing", "response Not Found"}},
+		{"/new/2", true, http.StatusForbidden, []string{"request /new/2", "response Forbidden"}},
+	}
+	for _, tc := range cases {
+		events = nil
+		req := httptest.NewRequest("GET", tc.path, nil)
+		if tc.blocked {
+			req.Header.Set("X-Blocked", "1")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d", tc.path, tc.code, w.Code)
+		}
+		if !reflect.DeepEqual(events, tc.events) {
+			t.Fatalf("%s: got events %q", tc.path, events)
+		}
+	}
+}
+
+func TestOnRouteMatchedAbort(t *testing.T) {
+	r := New()
+	r.OnRouteMatched(func(c *Context) {
+		if c.FullPath() == "/admin" {
+			c.Fail(http.StatusUnauthorized, "unauthorized")
+		}
+	})
+	r.GET("/admin", func(c *Context) {
+		t.Fatal("handler should not run")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/admin", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestOnError(t *testing.T) {
+	var got interface{}
+	var status int
+	r := New()
+	r.OnError(func(c *Context, err interface{}) {
+		got = err
+	})
+	r.OnResponse(func(c *Context) {
+		status = c.ResponseStatus()
+	})
+	r.Use(RecoveryWithWriter(nil))
+	r.GET("/", func(c *Context) {
+		panic("boom")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got != "boom" || status != http.StatusInternalServerError {
+		t.Fatalf("got error %v, status %d", got, status)
+	}
+}
@@ -0,0 +1,104 @@
+package gee
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// hostRouter 一个 Host 模式及其独立的路由树
+type hostRouter struct {
+	pattern string   // Host 模式，例如 api.example.com 或 {tenant}.example.com
+	labels  []string // 按 "." 分割后的片段，例如 ["{tenant}", "example", "com"]
+	isWild  bool     // 是否包含 {name} 形式的通配片段
+	group   *RouterGroup
+}
+
+// Host 创建一个按 Host 路由的分组，该分组拥有自己的路由树和中间件。
+// pattern 可以是精确的域名，例如 "api.example.com"，
+// 也可以包含 {name} 形式的通配片段，例如 "{tenant}.example.com"，匹配到的值可以通过 c.Param("tenant") 获取。
+// 请求的 Host 未匹配任何分组时，使用默认分组（即 engine 本身）处理。
+func (engine *Engine) Host(pattern string) *RouterGroup {
+	pattern = strings.ToLower(pattern)
+	for _, h := range engine.hosts {
+		if h.pattern == pattern { // 重复调用返回同一个分组
+			return h.group
+		}
+	}
+
+	labels := strings.Split(pattern, ".")
+	isWild := false
+	for _, label := range labels {
+		if label == "" {
+			panic(fmt.Sprintf("gee: invalid host pattern %q", pattern))
+		}
+		if label[0] == '{' {
+			if len(label) < 3 || label[len(label)-1] != '}' {
+				panic(fmt.Sprintf("gee: invalid host pattern %q", pattern))
+			}
+			isWild = true
+		}
+	}
+
+	group := &RouterGroup{engine: engine, router: newRouter()}
+	group.router.host = pattern
+	engine.groups = append(engine.groups, group)
+	engine.hosts = append(engine.hosts, &hostRouter{
+		pattern: pattern,
+		labels:  labels,
+		isWild:  isWild,
+		group:   group,
+	})
+	return group
+}
+
+// matchHost 根据请求的 Host 选择路由树，精确匹配优先于通配匹配，返回通配片段捕获到的参数
+func (engine *Engine) matchHost(host string) (*router, map[string]string) {
+	if len(engine.hosts) == 0 {
+		return engine.router, nil
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil { // 去掉端口
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, h := range engine.hosts {
+		if !h.isWild && h.pattern == host {
+			return h.group.router, nil
+		}
+	}
+
+	labels := strings.Split(host, ".")
+	for _, h := range engine.hosts {
+		if !h.isWild || len(h.labels) != len(labels) {
+			continue
+		}
+		if params, ok := h.match(labels); ok {
+			return h.group.router, params
+		}
+	}
+	return engine.router, nil
+}
+
+// match 逐个片段匹配 Host，{name} 片段匹配任意非空片段
+func (h *hostRouter) match(labels []string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for i, label := range h.labels {
+		if label[0] == '{' {
+			params[label[1:len(label)-1]] = labels[i]
+		} else if label != labels[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// routers 返回所有的路由树，默认路由树在最前面
+func (engine *Engine) routers() []*router {
+	routers := []*router{engine.router}
+	for _, h := range engine.hosts {
+		routers = append(routers, h.group.router)
+	}
+	return routers
+}
@@ -0,0 +1,68 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newHostEngine() *Engine {
+	r := New()
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "default")
+	})
+
+	api := r.Host("api.example.com")
+	api.Use(func(c *Context) {
+		c.SetHeader("X-Group", "api")
+	})
+	api.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "api")
+	})
+
+	tenant := r.Host("{tenant}.example.com")
+	tenant.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "%s:%s", c.Param("tenant"), c.Param("id"))
+	})
+	return r
+}
+
+func TestHostRouting(t *testing.T) {
+	r := newHostEngine()
+
+	cases := []struct {
+		host, path, body, group string
+	}{
+		{"api.example.com", "/", "api", "api"},
+		{"API.example.com:8080", "/", "api", "api"},
+		{"acme.example.com", "/users/42", "acme:42", ""},
+		{"other.org", "/", "default", ""},
+		{"a.b.example.com", "/", "default", ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		req.Host = c.host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != c.body {
+			t.Fatalf("%s%s: got body %q, want %q", c.host, c.path, w.Body.String(), c.body)
+		}
+		if got := w.Header().Get("X-Group"); got != c.group {
+			t.Fatalf("%s%s: got group %q, want %q", c.host, c.path, got, c.group)
+		}
+	}
+}
+
+func TestHostRoutes(t *testing.T) {
+	r := newHostEngine()
+	routes := r.Routes()
+	if len(routes) != 3 {
+		t.Fatalf("expect 3 routes, got %d", len(routes))
+	}
+	if routes[1].Host != "api.example.com" || routes[1].Middlewares != 1 {
+		t.Fatalf("unexpected route %+v", routes[1])
+	}
+	if routes[2].Host != "{tenant}.example.com" || routes[2].Pattern != "/users/:id" {
+		t.Fatalf("unexpected route %+v", routes[2])
+	}
+}
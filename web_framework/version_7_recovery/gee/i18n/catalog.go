@@ -0,0 +1,288 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoadGlob 加载匹配 pattern 的所有消息文件，文件名（不含扩展名）即语言，例如 locales/zh-CN.json
+func (b *Bundle) LoadGlob(pattern string) error {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := b.LoadMessageFile(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFS 从 fsys 中加载匹配 pattern 的所有消息文件，可以配合 embed.FS 将消息文件打包进二进制文件
+func (b *Bundle) LoadFS(fsys fs.FS, pattern string) error {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		if err := b.ParseMessageFile(path.Base(file), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadMessageFile 加载一个消息文件
+func (b *Bundle) LoadMessageFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return b.ParseMessageFile(filepath.Base(file), data)
+}
+
+// ParseMessageFile 解析消息文件的内容，name 为文件名，扩展名决定格式（.json 或 .toml），去掉扩展名后为语言。
+//
+// 值为字符串的是普通消息；值为对象且键全部是复数类别（zero、one、two、few、many、other）的是复数消息；
+// 其他对象是命名空间，其中的消息以点号连接，例如 {"home": {"title": "..."}} 中的消息为 home.title。
+func (b *Bundle) ParseMessageFile(name string, data []byte) error {
+	ext := path.Ext(name)
+	locale := strings.TrimSuffix(name, ext)
+	if locale == "" {
+		return fmt.Errorf("i18n: no locale in file name %q", name)
+	}
+
+	var raw map[string]interface{}
+	var err error
+	switch strings.ToLower(ext) {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".toml":
+		raw, err = parseTOML(string(data))
+	default:
+		return fmt.Errorf("i18n: unsupported message file %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("i18n: parse %s: %v", name, err)
+	}
+
+	messages := make(map[string]Message)
+	if err := flatten("", raw, messages); err != nil {
+		return fmt.Errorf("i18n: parse %s: %v", name, err)
+	}
+	for key, message := range messages {
+		b.AddMessage(locale, key, message)
+	}
+	return nil
+}
+
+// flatten 将嵌套的对象展开为以点号连接的消息
+func flatten(prefix string, raw map[string]interface{}, messages map[string]Message) error {
+	for key, value := range raw {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case string:
+			messages[key] = Message{"other": v}
+		case map[string]interface{}:
+			if message, ok := pluralMessage(v); ok {
+				messages[key] = message
+				continue
+			}
+			if err := flatten(key, v, messages); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("message %q must be a string or an object", key)
+		}
+	}
+	return nil
+}
+
+// pluralMessage 键全部是复数类别且值全部是字符串的对象为复数消息
+func pluralMessage(raw map[string]interface{}) (Message, bool) {
+	if len(raw) == 0 {
+		return nil, false
+	}
+	message := make(Message)
+	for key, value := range raw {
+		text, ok := value.(string)
+		if !ok || !isPluralCategory(key) {
+			return nil, false
+		}
+		message[key] = text
+	}
+	return message, true
+}
+
+func isPluralCategory(s string) bool {
+	for _, category := range pluralCategories {
+		if s == category {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTOML 解析消息文件用到的 TOML 子集：注释、[table] 和 [a.b] 表头、key = "value"、点号分隔的键、
+// 基本字符串（"..."，支持转义）和字面量字符串（'...'）
+func parseTOML(data string) (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	current := root
+	for i, line := range strings.Split(data, "\n") {
+		lineno := i + 1
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: invalid table header %q", lineno, line)
+			}
+			keys, err := parseTOMLKey(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			if current, err = tomlTable(root, keys); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			continue
+		}
+
+		eq := tomlKeyEnd(line)
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", lineno)
+		}
+		keys, err := parseTOMLKey(line[:eq])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		value, err := parseTOMLString(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		table, err := tomlTable(current, keys[:len(keys)-1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		last := keys[len(keys)-1]
+		if _, exists := table[last]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineno, last)
+		}
+		table[last] = value
+	}
+	return root, nil
+}
+
+// stripComment 去掉行尾的注释，字符串中的 # 不是注释
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; {
+		case quote != 0:
+			if ch == '\\' && quote == '"' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// tomlKeyEnd 返回键值对中等号的位置，带引号的键中的等号不算
+func tomlKeyEnd(line string) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '=':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseTOMLKey 解析以点号分隔的键，每一段可以是裸键或带引号的键，例如 home."page.title"
+func parseTOMLKey(s string) ([]string, error) {
+	var keys []string
+	s = strings.TrimSpace(s)
+	for {
+		var key string
+		if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") {
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated key %q", s)
+			}
+			key, s = s[1:end+1], strings.TrimSpace(s[end+2:])
+		} else {
+			end := strings.IndexByte(s, '.')
+			if end < 0 {
+				end = len(s)
+			}
+			key, s = strings.TrimSpace(s[:end]), strings.TrimSpace(s[end:])
+			if key == "" || strings.ContainsAny(key, " \t\"'") {
+				return nil, fmt.Errorf("invalid key %q", key)
+			}
+		}
+		keys = append(keys, key)
+		if s == "" {
+			return keys, nil
+		}
+		if s[0] != '.' {
+			return nil, fmt.Errorf("invalid key near %q", s)
+		}
+		s = strings.TrimSpace(s[1:])
+	}
+}
+
+func parseTOMLString(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return s[1 : len(s)-1], nil
+	}
+	return "", fmt.Errorf("value must be a string, got %q", s)
+}
+
+// tomlTable 返回 keys 对应的表，不存在时创建
+func tomlTable(table map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for _, key := range keys {
+		value, ok := table[key]
+		if !ok {
+			next := make(map[string]interface{})
+			table[key] = next
+			table = next
+			continue
+		}
+		next, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("key %q is not a table", key)
+		}
+		table = next
+	}
+	return table, nil
+}
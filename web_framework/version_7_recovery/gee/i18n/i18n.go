@@ -0,0 +1,291 @@
+// Package i18n 提供多语言支持：加载 JSON/TOML 格式的消息文件，根据查询参数、Cookie 或 Accept-Language 选择语言，
+// 并通过 c.T 和模板中的 t 函数翻译文本：
+//
+//	bundle := i18n.NewBundle("en")
+//	bundle.LoadGlob("locales/*.toml") // locales/en.toml、locales/zh-CN.toml
+//	r.Use(i18n.Middleware(bundle))
+//	r.GET("/", func(c *gee.Context) { c.String(200, c.T("apples", 3)) })
+//
+// 在模板中：{{t "welcome" .Name}}
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Message 一条消息，按复数类别（zero、one、two、few、many、other）保存不同的形式，没有复数形式的消息只有 other
+type Message map[string]string
+
+// Bundle 保存所有语言的消息和复数规则
+type Bundle struct {
+	mu            sync.RWMutex
+	defaultLocale string
+	messages      map[string]map[string]Message // locale -> key -> message
+	rules         map[string]PluralRule         // 自定义的复数规则，键为语言或语言代码
+	localizers    map[string]*Localizer
+}
+
+// NewBundle 创建消息集合，defaultLocale 为无法匹配请求语言时使用的语言，也是所有语言最后的回退语言
+func NewBundle(defaultLocale string) *Bundle {
+	return &Bundle{
+		defaultLocale: CanonicalLocale(defaultLocale),
+		messages:      make(map[string]map[string]Message),
+		rules:         make(map[string]PluralRule),
+		localizers:    make(map[string]*Localizer),
+	}
+}
+
+// DefaultLocale 返回默认语言
+func (b *Bundle) DefaultLocale() string {
+	return b.defaultLocale
+}
+
+// AddMessages 添加没有复数形式的消息
+func (b *Bundle) AddMessages(locale string, messages map[string]string) {
+	for key, text := range messages {
+		b.AddMessage(locale, key, Message{"other": text})
+	}
+}
+
+// AddMessage 添加一条消息，已存在的同名消息会被覆盖
+func (b *Bundle) AddMessage(locale string, key string, message Message) {
+	locale = CanonicalLocale(locale)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.messages[locale] == nil {
+		b.messages[locale] = make(map[string]Message)
+	}
+	b.messages[locale][key] = message
+}
+
+// SetPluralRule 为语言设置复数规则，覆盖内置的规则，lang 可以是 pt-BR 这样的完整语言，也可以是 pt 这样的语言代码
+func (b *Bundle) SetPluralRule(lang string, rule PluralRule) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rules[CanonicalLocale(lang)] = rule
+}
+
+// Locales 返回所有加载了消息的语言，按字母排序
+func (b *Bundle) Locales() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	locales := make([]string, 0, len(b.messages))
+	for locale := range b.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Localizer 返回语言对应的翻译器，同一种语言总是返回同一个翻译器
+func (b *Bundle) Localizer(locale string) *Localizer {
+	locale = CanonicalLocale(locale)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if l, ok := b.localizers[locale]; ok {
+		return l
+	}
+	l := &Localizer{bundle: b, locale: locale, chain: fallbackChain(locale, b.defaultLocale)}
+	b.localizers[locale] = l
+	return l
+}
+
+// Match 按顺序查找第一个支持的语言，找不到时返回默认语言。preferences 通常来自 ParseAcceptLanguage
+func (b *Bundle) Match(preferences ...string) string {
+	if locale, ok := b.match(preferences...); ok {
+		return locale
+	}
+	return b.defaultLocale
+}
+
+// match 对每个偏好的语言，依次尝试：完全相同的语言、去掉后缀的父语言（zh-Hant-TW -> zh-Hant -> zh）、语言代码相同的其他语言
+func (b *Bundle) match(preferences ...string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, pref := range preferences {
+		pref = CanonicalLocale(pref)
+		if pref == "" {
+			continue
+		}
+		for _, locale := range parents(pref) {
+			if _, ok := b.messages[locale]; ok {
+				return locale, true
+			}
+		}
+		lang := baseLanguage(pref)
+		var candidates []string
+		for locale := range b.messages {
+			if baseLanguage(locale) == lang {
+				candidates = append(candidates, locale)
+			}
+		}
+		if len(candidates) > 0 {
+			sort.Strings(candidates) // 保证结果稳定
+			return candidates[0], true
+		}
+	}
+	return "", false
+}
+
+func (b *Bundle) lookup(locale string, key string) (Message, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	message, ok := b.messages[locale][key]
+	return message, ok
+}
+
+func (b *Bundle) pluralRule(locale string) PluralRule {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, l := range parents(locale) {
+		if rule, ok := b.rules[l]; ok {
+			return rule
+		}
+	}
+	return builtinPluralRule(locale)
+}
+
+// Localizer 某种语言的翻译器，实现了 gee.Translator
+type Localizer struct {
+	bundle *Bundle
+	locale string
+	chain  []string // 查找消息的顺序，例如 zh-Hant-TW、zh-Hant、zh、en
+}
+
+// Locale 翻译器对应的语言
+func (l *Localizer) Locale() string {
+	return l.locale
+}
+
+// T 翻译 key。消息有复数形式时，args 中的第一个整数决定使用哪种形式；
+// args 不为空且消息中含有 % 时使用 fmt.Sprintf 格式化，参数顺序与原文不同时可以使用 %[2]s 这样的写法。
+// 当前语言找不到消息时按回退链查找，全部找不到时返回 key 本身。
+func (l *Localizer) T(key string, args ...interface{}) string {
+	for _, locale := range l.chain {
+		if message, ok := l.bundle.lookup(locale, key); ok {
+			return format(l.selectForm(locale, message, args), args)
+		}
+	}
+	return format(key, args)
+}
+
+// selectForm 选择消息的复数形式。数量为 0 且消息提供了 zero 形式时优先使用 zero，找不到对应形式时使用 other
+func (l *Localizer) selectForm(locale string, message Message, args []interface{}) string {
+	n, ok := pluralCount(args)
+	if !ok || len(message) == 1 {
+		return message.other()
+	}
+	if n == 0 {
+		if text, ok := message["zero"]; ok {
+			return text
+		}
+	}
+	if text, ok := message[l.bundle.pluralRule(locale)(n)]; ok {
+		return text
+	}
+	return message.other()
+}
+
+func (m Message) other() string {
+	if text, ok := m["other"]; ok {
+		return text
+	}
+	for _, category := range pluralCategories { // 没有 other 时使用任意一种已有的形式
+		if text, ok := m[category]; ok {
+			return text
+		}
+	}
+	return ""
+}
+
+func pluralCount(args []interface{}) (int64, bool) {
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case int:
+			return int64(v), true
+		case int8:
+			return int64(v), true
+		case int16:
+			return int64(v), true
+		case int32:
+			return int64(v), true
+		case int64:
+			return v, true
+		case uint:
+			return int64(v), true
+		case uint8:
+			return int64(v), true
+		case uint16:
+			return int64(v), true
+		case uint32:
+			return int64(v), true
+		case uint64:
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+func format(text string, args []interface{}) string {
+	if len(args) == 0 || !strings.Contains(text, "%") {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// CanonicalLocale 规范化语言标签：下划线替换为连字符，语言代码小写，书写系统首字母大写，地区大写，例如 zh_hant_tw -> zh-Hant-TW
+func CanonicalLocale(locale string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+	for i, part := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(part)
+		case len(part) == 4:
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		case len(part) == 2:
+			parts[i] = strings.ToUpper(part)
+		default:
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// parents 返回语言本身和依次去掉最后一段后的父语言，例如 zh-Hant-TW、zh-Hant、zh
+func parents(locale string) []string {
+	var result []string
+	for locale != "" {
+		result = append(result, locale)
+		i := strings.LastIndexByte(locale, '-')
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return result
+}
+
+func baseLanguage(locale string) string {
+	if i := strings.IndexByte(locale, '-'); i >= 0 {
+		return locale[:i]
+	}
+	return locale
+}
+
+// fallbackChain 查找消息的顺序：语言本身和父语言，然后是默认语言和它的父语言
+func fallbackChain(locale string, defaultLocale string) []string {
+	chain := parents(locale)
+	for _, l := range parents(defaultLocale) {
+		exists := false
+		for _, c := range chain {
+			exists = exists || c == l
+		}
+		if !exists {
+			chain = append(chain, l)
+		}
+	}
+	return chain
+}
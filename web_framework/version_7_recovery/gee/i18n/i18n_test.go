@@ -0,0 +1,156 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"geeweb/gee"
+)
+
+func newTestBundle(t *testing.T) *Bundle {
+	b := NewBundle("en")
+	if err := b.LoadGlob("testdata/locales/*"); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestLoadMessages(t *testing.T) {
+	b := newTestBundle(t)
+	if got := b.Locales(); !reflect.DeepEqual(got, []string{"en", "ru", "zh-CN"}) {
+		t.Fatalf("unexpected locales %v", got)
+	}
+
+	zh := b.Localizer("zh-CN")
+	cases := []struct {
+		l    *Localizer
+		key  string
+		args []interface{}
+		want string
+	}{
+		{zh, "welcome", []interface{}{"Gee"}, "欢迎，Gee！"},
+		{zh, "home.title", nil, "首页"},
+		{zh, "home.subtitle.with.dot", nil, `C:\path # 不是注释`},
+		{zh, "only_en", nil, "English only"}, // 回退到默认语言
+		{zh, "missing", nil, "missing"},
+		{b.Localizer("en"), "apples", []interface{}{0}, "no apples"},
+		{b.Localizer("en"), "apples", []interface{}{1}, "one apple"},
+		{b.Localizer("en"), "apples", []interface{}{5}, "5 apples"},
+		{zh, "apples", []interface{}{1}, "1 个苹果"},
+		{b.Localizer("ru"), "apples", []interface{}{21}, "21 яблоко"},
+		{b.Localizer("ru"), "apples", []interface{}{3}, "3 яблока"},
+		{b.Localizer("ru"), "apples", []interface{}{11}, "11 яблок"},
+	}
+	for _, tc := range cases {
+		if got := tc.l.T(tc.key, tc.args...); got != tc.want {
+			t.Errorf("%s %s %v: got %q, want %q", tc.l.Locale(), tc.key, tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for _, data := range []string{
+		"key",
+		"key = 1",
+		"[table",
+		"a = \"x\"\na = \"y\"",
+		"a = \"x\"\n[a]",
+	} {
+		if _, err := parseTOML(data); err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := ParseAcceptLanguage("en;q=0.8, fr-ch, *;q=0.5, de;q=0, fr;q=0.9")
+	if want := []string{"fr-CH", "fr", "en"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	b := newTestBundle(t)
+	cases := []struct {
+		prefs []string
+		want  string
+	}{
+		{[]string{"zh-CN"}, "zh-CN"},
+		{[]string{"zh-Hans-CN"}, "zh-CN"}, // 父语言 zh 不存在，匹配语言代码相同的 zh-CN
+		{[]string{"ru-RU"}, "ru"},
+		{[]string{"de", "ru"}, "ru"},
+		{[]string{"de"}, "en"},
+	}
+	for _, tc := range cases {
+		if got := b.Match(tc.prefs...); got != tc.want {
+			t.Errorf("%v: got %s, want %s", tc.prefs, got, tc.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	r := gee.New()
+	r.Use(Middleware(newTestBundle(t)))
+	r.GET("/", func(c *gee.Context) {
+		c.String(http.StatusOK, c.T("apples", 2))
+	})
+
+	cases := []struct {
+		url, cookie, acceptLanguage, want, locale string
+	}{
+		{"/", "", "", "2 apples", "en"},
+		{"/", "", "de;q=1, zh-cn;q=0.9", "2 个苹果", "zh-CN"},
+		{"/", "lang=ru", "zh-CN", "2 яблока", "ru"},
+		{"/?lang=zh_CN", "lang=ru", "en", "2 个苹果", "zh-CN"},
+		{"/?lang=xx", "", "ru", "2 яблока", "ru"}, // 不支持的查询参数，继续使用下一个来源
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", tc.url, nil)
+		if tc.cookie != "" {
+			req.Header.Set("Cookie", tc.cookie)
+		}
+		if tc.acceptLanguage != "" {
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != tc.want || w.Header().Get("Content-Language") != tc.locale {
+			t.Errorf("%+v: got %q %q", tc, w.Body.String(), w.Header().Get("Content-Language"))
+		}
+	}
+}
+
+func TestTemplateFunc(t *testing.T) {
+	r := gee.New()
+	r.LoadHTMLGlob("testdata/templates/*")
+	bundle := newTestBundle(t)
+
+	r.GET("/plain", func(c *gee.Context) {
+		c.HTML(http.StatusOK, "index", "Gee")
+	})
+	i18n := r.Group("/i18n")
+	i18n.Use(Middleware(bundle))
+	i18n.GET("/", func(c *gee.Context) {
+		c.HTML(http.StatusOK, "index", "Gee")
+	})
+
+	cases := []struct {
+		path, acceptLanguage, want string
+	}{
+		{"/plain", "", "<h1>home.title</h1><p>welcome</p>"},
+		{"/i18n/", "en", "<h1>Home</h1><p>Welcome, Gee!</p>"},
+		{"/i18n/", "zh-CN", "<h1>首页</h1><p>欢迎，Gee！</p>"},
+		{"/i18n/", "en", "<h1>Home</h1><p>Welcome, Gee!</p>"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		req.Header.Set("Accept-Language", tc.acceptLanguage)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Body.String(); got != tc.want+"\n" && got != tc.want {
+			t.Errorf("%s %s: got %q", tc.path, tc.acceptLanguage, got)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"geeweb/gee"
+)
+
+// Option Middleware 的可选配置
+type Option func(cfg *config)
+
+type config struct {
+	queryParam string
+	cookie     string
+}
+
+// WithQueryParam 指定语言的查询参数名，默认为 lang，例如 /?lang=zh-CN，传入空字符串表示不使用查询参数
+func WithQueryParam(name string) Option {
+	return func(cfg *config) {
+		cfg.queryParam = name
+	}
+}
+
+// WithCookie 指定语言的 Cookie 名，默认为 lang，传入空字符串表示不使用 Cookie
+func WithCookie(name string) Option {
+	return func(cfg *config) {
+		cfg.cookie = name
+	}
+}
+
+// Middleware 为每个请求选择语言并设置翻译器，之后可以使用 c.T 和模板中的 t 函数。
+// 语言的来源依次为：查询参数、Cookie、Accept-Language 请求头，都不支持时使用默认语言。
+// 选中的语言写入 Content-Language 响应头。
+func Middleware(bundle *Bundle, opts ...Option) gee.HandlerFunc {
+	cfg := &config{queryParam: "lang", cookie: "lang"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(c *gee.Context) {
+		locale := negotiate(c, bundle, cfg)
+		c.SetTranslator(bundle.Localizer(locale))
+		c.SetHeader("Content-Language", locale)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+func negotiate(c *gee.Context, bundle *Bundle, cfg *config) string {
+	if cfg.queryParam != "" {
+		if value := c.Query(cfg.queryParam); value != "" {
+			if locale, ok := bundle.match(value); ok {
+				return locale
+			}
+		}
+	}
+	if cfg.cookie != "" {
+		if cookie, err := c.Req.Cookie(cfg.cookie); err == nil && cookie.Value != "" {
+			if locale, ok := bundle.match(cookie.Value); ok {
+				return locale
+			}
+		}
+	}
+	return bundle.Match(ParseAcceptLanguage(c.Req.Header.Get("Accept-Language"))...)
+}
+
+// ParseAcceptLanguage 解析 Accept-Language 请求头，按权重从高到低返回语言，权重相同时保持原有顺序。
+// 权重为 0 的语言和通配符 * 会被忽略，例如 "fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5" 返回 [fr-CH fr en]
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil || v < 0 || v > 1 {
+					v = 0 // 无法解析的权重视为不接受
+				}
+				q = v
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = CanonicalLocale(t.tag)
+	}
+	return result
+}
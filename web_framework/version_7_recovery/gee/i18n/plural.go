@@ -0,0 +1,117 @@
+package i18n
+
+// PluralRule 复数规则，返回数量 n 对应的复数类别：zero、one、two、few、many 或 other，
+// 参考 Unicode CLDR 的复数规则（只考虑整数）
+type PluralRule func(n int64) string
+
+var pluralCategories = []string{"zero", "one", "two", "few", "many", "other"}
+
+// builtinPluralRule 内置的常见语言复数规则，未知的语言按英语处理
+func builtinPluralRule(locale string) PluralRule {
+	if rule, ok := builtinPluralRules[locale]; ok {
+		return rule
+	}
+	if rule, ok := builtinPluralRules[baseLanguage(locale)]; ok {
+		return rule
+	}
+	return pluralOneOther
+}
+
+var builtinPluralRules = map[string]PluralRule{
+	// 没有复数变化
+	"zh": pluralOther, "ja": pluralOther, "ko": pluralOther, "th": pluralOther,
+	"vi": pluralOther, "id": pluralOther, "ms": pluralOther,
+	// 1 为单数
+	"en": pluralOneOther, "de": pluralOneOther, "nl": pluralOneOther, "sv": pluralOneOther,
+	"da": pluralOneOther, "nb": pluralOneOther, "no": pluralOneOther, "it": pluralOneOther,
+	"es": pluralOneOther, "pt-PT": pluralOneOther, "el": pluralOneOther, "fi": pluralOneOther,
+	"hu": pluralOneOther, "tr": pluralOneOther,
+	// 0 和 1 为单数
+	"fr": pluralZeroOneOther, "pt": pluralZeroOneOther,
+	// 斯拉夫语系
+	"ru": pluralEastSlavic, "uk": pluralEastSlavic, "be": pluralEastSlavic,
+	"pl": pluralPolish,
+	"cs": pluralCzech, "sk": pluralCzech,
+	"ar": pluralArabic,
+}
+
+func pluralOther(n int64) string {
+	return "other"
+}
+
+func pluralOneOther(n int64) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+func pluralZeroOneOther(n int64) string {
+	if n == 0 || n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+// pluralEastSlavic 俄语等：1、21、31 为 one，2-4、22-24 为 few，其余为 many
+func pluralEastSlavic(n int64) string {
+	mod10, mod100 := abs(n)%10, abs(n)%100
+	switch {
+	case mod10 == 1 && mod100 != 11:
+		return "one"
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return "few"
+	default:
+		return "many"
+	}
+}
+
+// pluralPolish 波兰语：只有 1 为 one，2-4、22-24 为 few，其余为 many
+func pluralPolish(n int64) string {
+	mod10, mod100 := abs(n)%10, abs(n)%100
+	switch {
+	case n == 1:
+		return "one"
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return "few"
+	default:
+		return "many"
+	}
+}
+
+// pluralCzech 捷克语：1 为 one，2-4 为 few，其余为 other
+func pluralCzech(n int64) string {
+	switch {
+	case n == 1:
+		return "one"
+	case n >= 2 && n <= 4:
+		return "few"
+	default:
+		return "other"
+	}
+}
+
+func pluralArabic(n int64) string {
+	mod100 := abs(n) % 100
+	switch {
+	case n == 0:
+		return "zero"
+	case n == 1:
+		return "one"
+	case n == 2:
+		return "two"
+	case mod100 >= 3 && mod100 <= 10:
+		return "few"
+	case mod100 >= 11:
+		return "many"
+	default:
+		return "other"
+	}
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
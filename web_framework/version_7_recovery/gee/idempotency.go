@@ -0,0 +1,296 @@
+package gee
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord 一个 Idempotency-Key 对应的记录。请求处理中时 Completed 为 false，只有 Fingerprint 有意义
+type IdempotencyRecord struct {
+	Fingerprint string // 请求方法、路径和请求体的摘要，用于发现同一个 key 被用于不同的请求
+	Completed   bool
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+// IdempotencyStore Idempotency 中间件的存储，可以替换为 Redis 等外部存储，多个实例之间共享
+type IdempotencyStore interface {
+	// Lock 原子地检查并锁定 key：key 不存在时写入处理中的记录（lockTTL 后过期，防止进程崩溃后一直锁定），返回 locked 为 true；
+	// 否则返回已有的记录，locked 为 false
+	Lock(key string, fingerprint string, lockTTL time.Duration) (record *IdempotencyRecord, locked bool, err error)
+	// Save 保存处理完成的响应，ttl 后过期
+	Save(key string, record *IdempotencyRecord, ttl time.Duration) error
+	// Unlock 删除处理中的记录，处理失败时调用，使客户端可以重试
+	Unlock(key string) error
+}
+
+// MemoryIdempotencyStore 内存中的 IdempotencyStore，只适用于单实例部署。
+// Lock 时检查对应 key 是否过期，其余过期的记录由后台每分钟清理一次，不再使用时调用 Close 停止清理
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*memoryIdempotencyEntry
+	stop    chan struct{}
+	once    sync.Once
+}
+
+type memoryIdempotencyEntry struct {
+	record  *IdempotencyRecord
+	expires time.Time
+}
+
+// memoryIdempotencySweepInterval 后台清理过期记录的间隔
+const memoryIdempotencySweepInterval = time.Minute
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	s := &MemoryIdempotencyStore{
+		records: make(map[string]*memoryIdempotencyEntry),
+		stop:    make(chan struct{}),
+	}
+	go s.sweepLoop(memoryIdempotencySweepInterval)
+	return s
+}
+
+// Close 停止后台清理
+func (s *MemoryIdempotencyStore) Close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+func (s *MemoryIdempotencyStore) sweepLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.sweep()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// sweep 删除所有过期的记录
+func (s *MemoryIdempotencyStore) sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, entry := range s.records {
+		if now.After(entry.expires) {
+			delete(s.records, key)
+		}
+	}
+}
+
+func (s *MemoryIdempotencyStore) Lock(key string, fingerprint string, lockTTL time.Duration) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if entry, ok := s.records[key]; ok && !now.After(entry.expires) {
+		return entry.record, false, nil
+	}
+	record := &IdempotencyRecord{Fingerprint: fingerprint}
+	s.records[key] = &memoryIdempotencyEntry{record: record, expires: now.Add(lockTTL)}
+	return record, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Save(key string, record *IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = &memoryIdempotencyEntry{record: record, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Unlock(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.records[key]; ok && !entry.record.Completed {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+// IdempotencyOption Idempotency 中间件的可选配置
+type IdempotencyOption func(cfg *idempotencyConfig)
+
+type idempotencyConfig struct {
+	ttl         time.Duration
+	lockTimeout time.Duration
+	wait        time.Duration
+	methods     map[string]bool
+	keyFunc     func(c *Context, key string) string
+}
+
+// IdempotencyTTL 保存响应的时长，默认 24 小时
+func IdempotencyTTL(ttl time.Duration) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.ttl = ttl
+	}
+}
+
+// IdempotencyLockTimeout 处理中的记录的过期时间，应当大于请求的最长处理时间，默认 1 分钟
+func IdempotencyLockTimeout(timeout time.Duration) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.lockTimeout = timeout
+	}
+}
+
+// IdempotencyWait 相同 key 的请求正在处理时，最多等待 wait 后返回第一个请求的响应，默认不等待，直接返回 409
+func IdempotencyWait(wait time.Duration) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.wait = wait
+	}
+}
+
+// IdempotencyMethods 需要检查 Idempotency-Key 的请求方法，默认为 POST 和 PATCH
+func IdempotencyMethods(methods ...string) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.methods = make(map[string]bool)
+		for _, m := range methods {
+			cfg.methods[m] = true
+		}
+	}
+}
+
+// IdempotencyKeyFunc 根据请求和 Idempotency-Key 生成存储中使用的 key，例如加上认证得到的用户 ID，
+// 使不同的客户端即使选择了相同的 Idempotency-Key 也不会拿到彼此的响应。返回空字符串时不检查该请求。
+// 默认直接使用 Idempotency-Key，所有客户端共用同一个命名空间
+func IdempotencyKeyFunc(fn func(c *Context, key string) string) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.keyFunc = fn
+	}
+}
+
+// Idempotency 根据 Idempotency-Key 请求头保证请求只被处理一次，客户端重试时返回第一次的响应，并带上 Idempotent-Replayed: true。
+//   - 相同 key 的请求正在处理时返回 409，或者按 IdempotencyWait 等待第一个请求完成；
+//   - 相同 key 用于不同的请求（方法、路径、查询参数或请求体不同）时返回 422；
+//   - 响应为 5xx 或处理函数 panic 时不保存响应，客户端可以使用同一个 key 重试。
+//
+// 没有 Idempotency-Key 请求头的请求不受影响。默认所有客户端共用 key 的命名空间，
+// 多个用户共用服务时应当通过 IdempotencyKeyFunc 按用户区分。
+func Idempotency(store IdempotencyStore, opts ...IdempotencyOption) HandlerFunc {
+	cfg := &idempotencyConfig{
+		ttl:         24 * time.Hour,
+		lockTimeout: time.Minute,
+		methods:     map[string]bool{http.MethodPost: true, http.MethodPatch: true},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(c *Context) {
+		key := c.Req.Header.Get("Idempotency-Key")
+		if key != "" && cfg.keyFunc != nil {
+			key = cfg.keyFunc(c, key)
+		}
+		if key == "" || !cfg.methods[c.Method] {
+			c.Next()
+			return
+		}
+
+		body, err := ioutil.ReadAll(c.Req.Body)
+		if err != nil {
+			c.Fail(bodyErrorStatus(err), err.Error())
+			return
+		}
+		c.Req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(c.Method, c.Path, c.Req.URL.RawQuery, body)
+
+		record, locked, err := lockIdempotencyKey(c, store, cfg, key, fingerprint)
+		if err != nil {
+			c.Error(err)
+			c.Fail(http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if record.Fingerprint != fingerprint {
+			c.Fail(http.StatusUnprocessableEntity, "Idempotency-Key has already been used for a different request")
+			return
+		}
+		if !locked {
+			if !record.Completed {
+				c.Fail(http.StatusConflict, "a request with the same Idempotency-Key is in progress")
+				return
+			}
+			replayIdempotentResponse(c, record)
+			return
+		}
+
+		saved := false
+		writer := c.Writer
+		defer func() { // 处理失败（包括 panic）时释放 key，允许客户端重试
+			c.Writer = writer
+			if !saved {
+				_ = store.Unlock(key)
+			}
+		}()
+
+		buffer := &cacheWriter{header: make(http.Header)}
+		c.Writer = buffer
+		c.Next()
+		c.Writer = writer
+
+		status := buffer.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		record = &IdempotencyRecord{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			Status:      status,
+			Header:      buffer.header.Clone(), // 保存的记录不能与之后写入响应的请求头共用
+			Body:        buffer.body.Bytes(),
+		}
+		if status < http.StatusInternalServerError {
+			if err := store.Save(key, record, cfg.ttl); err != nil {
+				c.Error(err)
+			} else {
+				saved = true
+			}
+		}
+		writeIdempotentResponse(c, record)
+	}
+}
+
+// lockIdempotencyKey 锁定 key，配置了 IdempotencyWait 时等待处理中的请求完成
+func lockIdempotencyKey(c *Context, store IdempotencyStore, cfg *idempotencyConfig, key, fingerprint string) (*IdempotencyRecord, bool, error) {
+	deadline := time.Now().Add(cfg.wait)
+	for {
+		record, locked, err := store.Lock(key, fingerprint, cfg.lockTimeout)
+		if err != nil || locked || record.Completed || record.Fingerprint != fingerprint || !time.Now().Before(deadline) {
+			return record, locked, err
+		}
+		select {
+		case <-c.Req.Context().Done():
+			return record, locked, nil
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// requestFingerprint 请求的指纹，由方法、路径、查询参数和请求体计算，用于发现 key 被用于不同的请求
+func requestFingerprint(method, path, query string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "?" + query + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replayIdempotentResponse(c *Context, record *IdempotencyRecord) {
+	c.SetHeader("Idempotent-Replayed", "true")
+	writeIdempotentResponse(c, record)
+	c.Abort()
+}
+
+func writeIdempotentResponse(c *Context, record *IdempotencyRecord) {
+	header := c.Writer.Header()
+	for key, values := range record.Header.Clone() { // 之后的中间件修改响应头时不能影响保存的记录
+		header[key] = values
+	}
+	c.Status(record.Status)
+	_, _ = c.Writer.Write(record.Body)
+}
@@ -0,0 +1,216 @@
+package gee
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func postIdempotent(r *Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/orders", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency(t *testing.T) {
+	created := 0
+	r := New()
+	r.Use(Idempotency(NewMemoryIdempotencyStore()))
+	r.POST("/orders", func(c *Context) {
+		body, _ := ioutil.ReadAll(c.Req.Body)
+		if string(body) == "fail" {
+			c.Fail(http.StatusInternalServerError, "try again")
+			return
+		}
+		created++
+		c.SetHeader("Location", "/orders/1")
+		c.JSON(http.StatusCreated, H{"id": created, "body": string(body)})
+	})
+
+	first := postIdempotent(r, "k1", "apple")
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("unexpected first response %d %v", first.Code, first.Header())
+	}
+	replay := postIdempotent(r, "k1", "apple")
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() ||
+		replay.Header().Get("Location") != "/orders/1" || replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("unexpected replay %d %v %s", replay.Code, replay.Header(), replay.Body.String())
+	}
+	if created != 1 {
+		t.Fatalf("handler should run once, ran %d times", created)
+	}
+
+	if w := postIdempotent(r, "k1", "banana"); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("different body: expected 422, got %d", w.Code)
+	}
+	if w := postIdempotent(r, "k2", "banana"); w.Code != http.StatusCreated || created != 2 {
+		t.Fatalf("new key: expected 201, got %d", w.Code)
+	}
+
+	// 5xx 不保存，同一个 key 可以重试
+	postIdempotent(r, "k3", "fail")
+	if w := postIdempotent(r, "k3", "fail"); w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatal("5xx response should not be replayed")
+	}
+
+	// 没有 Idempotency-Key 时不受影响
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/orders", strings.NewReader("apple")))
+	if w.Code != http.StatusCreated || created != 3 {
+		t.Fatalf("request without key: got %d, created %d", w.Code, created)
+	}
+}
+
+func TestIdempotencyConcurrent(t *testing.T) {
+	newEngine := func(opts ...IdempotencyOption) (*Engine, chan struct{}) {
+		release := make(chan struct{})
+		r := New()
+		r.Use(Idempotency(NewMemoryIdempotencyStore(), opts...))
+		r.POST("/orders", func(c *Context) {
+			<-release
+			c.String(http.StatusCreated, "created")
+		})
+		return r, release
+	}
+
+	r, release := newEngine()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		postIdempotent(r, "k", "body")
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if w := postIdempotent(r, "k", "body"); w.Code != http.StatusConflict {
+		t.Fatalf("in-flight duplicate: expected 409, got %d", w.Code)
+	}
+	close(release)
+	wg.Wait()
+
+	r, release = newEngine(IdempotencyWait(time.Second))
+	go postIdempotent(r, "k", "body")
+	time.Sleep(50 * time.Millisecond)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	w := postIdempotent(r, "k", "body")
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("waiting duplicate: expected replayed 201, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestMemoryIdempotencyStoreExpire(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	if _, locked, _ := store.Lock("k", "f", 10*time.Millisecond); !locked {
+		t.Fatal("expected lock")
+	}
+	if _, locked, _ := store.Lock("k", "f", time.Minute); locked {
+		t.Fatal("key should be locked")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, locked, _ := store.Lock("k", "f", time.Minute); !locked {
+		t.Fatal("expired lock should be released")
+	}
+}
+
+func TestIdempotencyHeaderAliasing(t *testing.T) {
+	r := New()
+	store := NewMemoryIdempotencyStore()
+	defer store.Close()
+	r.Use(func(c *Context) {
+		c.Next()
+		// 响应写出之后原地修改请求头，不能影响保存的记录
+		if values := c.Writer.Header()["Location"]; len(values) > 0 {
+			values[0] = "https://example.com" + values[0]
+		}
+	}, Idempotency(store))
+	r.POST("/orders", func(c *Context) {
+		c.SetHeader("Location", "/orders/1")
+		c.Status(http.StatusCreated)
+	})
+
+	for i := 0; i < 3; i++ {
+		w := postIdempotent(r, "k", "body")
+		if location := w.Result().Header.Get("Location"); location != "/orders/1" { // 写出响应时的请求头
+			t.Fatalf("request %d: unexpected Location %q", i, location)
+		}
+	}
+}
+
+func TestMemoryIdempotencyStoreSweep(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	defer store.Close()
+	store.Lock("expired", "f", time.Millisecond)
+	store.Lock("alive", "f", time.Minute)
+	time.Sleep(5 * time.Millisecond)
+	store.sweep()
+	if _, ok := store.records["expired"]; ok || len(store.records) != 1 {
+		t.Fatalf("expired record should be removed, got %v", store.records)
+	}
+	store.Close()
+	store.Close() // 可以重复调用
+}
+
+func TestIdempotencyQuery(t *testing.T) {
+	r := New()
+	store := NewMemoryIdempotencyStore()
+	defer store.Close()
+	r.Use(Idempotency(store))
+	r.POST("/orders", func(c *Context) {
+		c.String(http.StatusCreated, "region %s", c.Query("region"))
+	})
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader("body"))
+		req.Header.Set("Idempotency-Key", "k")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := post("/orders?region=eu"); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", w.Code)
+	}
+	if w := post("/orders?region=us"); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("same key with a different query: expected 422, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestIdempotencyKeyFunc(t *testing.T) {
+	created := 0
+	r := New()
+	store := NewMemoryIdempotencyStore()
+	defer store.Close()
+	r.Use(Idempotency(store, IdempotencyKeyFunc(func(c *Context, key string) string {
+		return c.Req.Header.Get("X-User") + ":" + key
+	})))
+	r.POST("/orders", func(c *Context) {
+		created++
+		c.String(http.StatusCreated, "order %d for %s", created, c.Req.Header.Get("X-User"))
+	})
+
+	post := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/orders", strings.NewReader("body"))
+		req.Header.Set("Idempotency-Key", "k")
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	// 不同用户使用相同的 key 互不影响
+	if w := post("alice"); w.Body.String() != "order 1 for alice" {
+		t.Fatalf("unexpected response %q", w.Body.String())
+	}
+	if w := post("bob"); w.Body.String() != "order 2 for bob" || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("another user's key should not be replayed, got %q", w.Body.String())
+	}
+	if w := post("alice"); w.Body.String() != "order 1 for alice" || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected alice's response to be replayed, got %q", w.Body.String())
+	}
+}
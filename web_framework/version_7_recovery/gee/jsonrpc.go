@@ -0,0 +1,357 @@
+package gee
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// JSON-RPC 2.0 规范定义的错误码
+const (
+	JSONRPCParseError     = -32700 // 请求不是合法的 JSON
+	JSONRPCInvalidRequest = -32600 // 请求不是合法的 Request 对象
+	JSONRPCMethodNotFound = -32601 // 方法不存在
+	JSONRPCInvalidParams  = -32602 // 参数不正确
+	JSONRPCInternalError  = -32603 // 服务端内部错误，例如方法 panic
+	JSONRPCServerError    = -32000 // 方法返回了普通的 error（-32000 到 -32099 为实现自定义的服务端错误）
+)
+
+// JSONRPCError JSON-RPC 的错误对象。方法返回 *JSONRPCError 时原样返回给调用方，可以使用自定义的错误码
+type JSONRPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("jsonrpc: %s (%d)", e.Message, e.Code)
+}
+
+// NewJSONRPCError 创建错误对象
+func NewJSONRPCError(code int, message string, data interface{}) *JSONRPCError {
+	return &JSONRPCError{Code: code, Message: message, Data: data}
+}
+
+var (
+	typeOfContext    = reflect.TypeOf((*Context)(nil))
+	typeOfStdContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+	typeOfError      = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// rpcMethod 注册的方法，fn 已经绑定了接收者，形如 func(ctx, params) (result, error) 或 func(ctx) (result, error)
+type rpcMethod struct {
+	fn        reflect.Value
+	ctxType   reflect.Type // *gee.Context 或 context.Context
+	paramType reflect.Type // 没有参数时为 nil
+}
+
+// JSONRPCRegistry 保存可以通过 JSON-RPC 调用的方法
+type JSONRPCRegistry struct {
+	mu      sync.RWMutex
+	methods map[string]*rpcMethod
+}
+
+func NewJSONRPCRegistry() *JSONRPCRegistry {
+	return &JSONRPCRegistry{methods: make(map[string]*rpcMethod)}
+}
+
+// Register 注册 rcvr 所有符合要求的导出方法，方法名为 "类型名.方法名"，例如 "Arith.Add"。方法必须满足：
+//
+//	func (t *T) MethodName(ctx *gee.Context, params P) (R, error)
+//
+// ctx 也可以是 context.Context，此时传入 c.Req.Context()；params 可以省略。没有符合要求的方法时返回错误
+func (r *JSONRPCRegistry) Register(rcvr interface{}) error {
+	name := reflect.Indirect(reflect.ValueOf(rcvr)).Type().Name()
+	return r.RegisterName(name, rcvr)
+}
+
+// RegisterName 与 Register 相同，但使用 name 代替类型名
+func (r *JSONRPCRegistry) RegisterName(name string, rcvr interface{}) error {
+	v := reflect.ValueOf(rcvr)
+	t := v.Type()
+	count := 0
+	for i := 0; i < t.NumMethod(); i++ {
+		method := t.Method(i)
+		if !ast.IsExported(method.Name) {
+			continue
+		}
+		m, err := newRPCMethod(v.Method(i))
+		if err != nil { // 与 geerpc 一样跳过不符合要求的方法
+			continue
+		}
+		r.add(name+"."+method.Name, m)
+		count++
+	}
+	if count == 0 {
+		return fmt.Errorf("gee: %s has no exported method of the form func(ctx, params) (result, error)", name)
+	}
+	return nil
+}
+
+// RegisterFunc 注册函数，fn 的签名要求与 Register 中的方法相同
+func (r *JSONRPCRegistry) RegisterFunc(name string, fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return fmt.Errorf("gee: %s is not a function", name)
+	}
+	m, err := newRPCMethod(v)
+	if err != nil {
+		return fmt.Errorf("gee: %s: %v", name, err)
+	}
+	r.add(name, m)
+	return nil
+}
+
+// Methods 返回所有方法名，按字母排序
+func (r *JSONRPCRegistry) Methods() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.methods))
+	for name := range r.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *JSONRPCRegistry) add(name string, m *rpcMethod) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.methods[name] = m
+}
+
+func (r *JSONRPCRegistry) lookup(name string) (*rpcMethod, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.methods[name]
+	return m, ok
+}
+
+// newRPCMethod 检查函数的签名：1 到 2 个参数，第 1 个是 *gee.Context 或 context.Context；2 个返回值，第 2 个是 error
+func newRPCMethod(fn reflect.Value) (*rpcMethod, error) {
+	t := fn.Type()
+	if t.NumIn() < 1 || t.NumIn() > 2 || t.NumOut() != 2 || t.Out(1) != typeOfError {
+		return nil, errors.New("signature must be func(ctx, params) (result, error)")
+	}
+	if t.In(0) != typeOfContext && t.In(0) != typeOfStdContext {
+		return nil, errors.New("first argument must be *gee.Context or context.Context")
+	}
+	m := &rpcMethod{fn: fn, ctxType: t.In(0)}
+	if t.NumIn() == 2 {
+		m.paramType = t.In(1)
+	}
+	return m, nil
+}
+
+// jsonrpcRequest 请求对象，ID 为空表示字段不存在，即通知（notification），"null" 表示 id 为 null
+type jsonrpcRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonrpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+var jsonNull = json.RawMessage("null")
+
+// JSONRPC 返回处理 JSON-RPC 2.0 请求的处理函数，例如 r.POST("/rpc", gee.JSONRPC(registry))。
+// 支持批量请求和通知，通知不返回响应，请求全部是通知时返回 204。方法通过 ctx 可以拿到当前请求的 Context，
+// 因此分组上的鉴权等中间件同样生效。
+func JSONRPC(registry *JSONRPCRegistry) HandlerFunc {
+	return func(c *Context) {
+		body, err := ioutil.ReadAll(c.Req.Body)
+		if err != nil {
+			c.Fail(bodyErrorStatus(err), err.Error())
+			return
+		}
+		body = bytes.TrimSpace(body)
+
+		var result interface{}
+		if len(body) > 0 && body[0] == '[' {
+			var batch []json.RawMessage
+			if err := json.Unmarshal(body, &batch); err != nil {
+				result = jsonrpcErrorResponse(jsonNull, JSONRPCParseError, "Parse error", err.Error())
+			} else if len(batch) == 0 {
+				result = jsonrpcErrorResponse(jsonNull, JSONRPCInvalidRequest, "Invalid Request", "empty batch")
+			} else {
+				var responses []*jsonrpcResponse
+				for _, raw := range batch {
+					if resp := registry.handle(c, raw); resp != nil {
+						responses = append(responses, resp)
+					}
+				}
+				if len(responses) > 0 {
+					result = responses
+				}
+			}
+		} else if !json.Valid(body) {
+			result = jsonrpcErrorResponse(jsonNull, JSONRPCParseError, "Parse error", nil)
+		} else if resp := registry.handle(c, body); resp != nil {
+			result = resp
+		}
+
+		if result == nil { // 全部是通知
+			c.Status(http.StatusNoContent)
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// handle 处理单个请求，请求是通知时返回 nil
+func (r *JSONRPCRegistry) handle(c *Context, raw json.RawMessage) *jsonrpcResponse {
+	var req jsonrpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.Version != "2.0" || req.Method == "" {
+		return jsonrpcErrorResponse(jsonNull, JSONRPCInvalidRequest, "Invalid Request", nil)
+	}
+	notification := len(req.ID) == 0
+	id := req.ID
+	if notification {
+		id = jsonNull
+	} else if !validJSONRPCID(id) {
+		return jsonrpcErrorResponse(jsonNull, JSONRPCInvalidRequest, "Invalid Request", "id must be a string, number or null")
+	}
+	if len(req.Params) > 0 && req.Params[0] != '[' && req.Params[0] != '{' {
+		return jsonrpcErrorResponse(id, JSONRPCInvalidRequest, "Invalid Request", "params must be an array or an object")
+	}
+
+	resp := r.call(c, req.Method, req.Params)
+	if notification {
+		return nil
+	}
+	resp.ID = id
+	return resp
+}
+
+func (r *JSONRPCRegistry) call(c *Context, name string, params json.RawMessage) (resp *jsonrpcResponse) {
+	m, ok := r.lookup(name)
+	if !ok {
+		return jsonrpcErrorResponse(nil, JSONRPCMethodNotFound, "Method not found", name)
+	}
+	args := []reflect.Value{reflect.ValueOf(c)}
+	if m.ctxType == typeOfStdContext {
+		args[0] = reflect.ValueOf(c.Req.Context())
+	}
+	if m.paramType != nil {
+		argv, err := decodeJSONRPCParams(m.paramType, params)
+		if err != nil {
+			return jsonrpcErrorResponse(nil, JSONRPCInvalidParams, "Invalid params", err.Error())
+		}
+		args = append(args, argv)
+	} else if !emptyJSONRPCParams(params) {
+		return jsonrpcErrorResponse(nil, JSONRPCInvalidParams, "Invalid params", "method takes no params")
+	}
+
+	defer func() {
+		if err := recover(); err != nil { // 方法 panic 时不影响批量请求中的其他请求，也不向调用方暴露细节
+			c.Error(fmt.Errorf("jsonrpc: %s panicked: %v", name, err))
+			resp = jsonrpcErrorResponse(nil, JSONRPCInternalError, "Internal error", nil)
+		}
+	}()
+	out := m.fn.Call(args)
+	if errv := out[1].Interface(); errv != nil {
+		var rpcErr *JSONRPCError
+		if errors.As(errv.(error), &rpcErr) {
+			return &jsonrpcResponse{Version: "2.0", Error: rpcErr}
+		}
+		return jsonrpcErrorResponse(nil, JSONRPCServerError, errv.(error).Error(), nil)
+	}
+	result, err := json.Marshal(out[0].Interface())
+	if err != nil {
+		return jsonrpcErrorResponse(nil, JSONRPCInternalError, "Internal error", err.Error())
+	}
+	return &jsonrpcResponse{Version: "2.0", Result: result}
+}
+
+func jsonrpcErrorResponse(id json.RawMessage, code int, message string, data interface{}) *jsonrpcResponse {
+	return &jsonrpcResponse{Version: "2.0", Error: NewJSONRPCError(code, message, data), ID: id}
+}
+
+// validJSONRPCID id 只能是字符串、数字或 null
+func validJSONRPCID(id json.RawMessage) bool {
+	switch id[0] {
+	case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	}
+	return false
+}
+
+func emptyJSONRPCParams(params json.RawMessage) bool {
+	s := string(bytes.Join(bytes.Fields(params), nil))
+	return s == "" || s == "null" || s == "[]" || s == "{}"
+}
+
+// decodeJSONRPCParams 将参数解码为 typ 类型的值。对象按字段名解码（不允许未知的字段）；
+// 数组在 typ 为结构体时按导出字段的顺序依次解码，在 typ 为单个值时取数组唯一的元素
+func decodeJSONRPCParams(typ reflect.Type, params json.RawMessage) (reflect.Value, error) {
+	base := typ
+	if base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+	argv := reflect.New(base)
+
+	if len(params) == 0 {
+		// 没有参数时使用零值
+	} else if params[0] == '[' && base.Kind() != reflect.Slice && base.Kind() != reflect.Array {
+		var items []json.RawMessage
+		if err := json.Unmarshal(params, &items); err != nil {
+			return reflect.Value{}, err
+		}
+		if base.Kind() == reflect.Struct {
+			fields := positionalFields(base)
+			if len(items) > len(fields) {
+				return reflect.Value{}, fmt.Errorf("too many params: expected at most %d, got %d", len(fields), len(items))
+			}
+			for i, item := range items {
+				if err := json.Unmarshal(item, argv.Elem().FieldByIndex(fields[i]).Addr().Interface()); err != nil {
+					return reflect.Value{}, fmt.Errorf("param %d: %v", i, err)
+				}
+			}
+		} else {
+			if len(items) != 1 {
+				return reflect.Value{}, fmt.Errorf("expected 1 param, got %d", len(items))
+			}
+			if err := json.Unmarshal(items[0], argv.Interface()); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(params))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(argv.Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	if typ.Kind() == reflect.Ptr {
+		return argv, nil
+	}
+	return argv.Elem(), nil
+}
+
+// positionalFields 按声明顺序返回可以按位置赋值的导出字段
+func positionalFields(t reflect.Type) [][]int {
+	var fields [][]int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("json") == "-" {
+			continue
+		}
+		fields = append(fields, f.Index)
+	}
+	return fields
+}
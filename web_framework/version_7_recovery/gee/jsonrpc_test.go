@@ -0,0 +1,185 @@
+package gee
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type Arith struct{}
+
+type ArithArgs struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func (Arith) Add(c *Context, args ArithArgs) (int, error) {
+	return args.A + args.B, nil
+}
+
+func (Arith) Div(ctx context.Context, args *ArithArgs) (float64, error) {
+	if args.B == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return float64(args.A) / float64(args.B), nil
+}
+
+func (Arith) Whoami(c *Context) (string, error) {
+	return c.Req.Header.Get("X-User"), nil
+}
+
+func (Arith) Panic(c *Context) (interface{}, error) {
+	panic("boom")
+}
+
+// Ignored 签名不符合要求的方法不会被注册
+func (Arith) Ignored(a int) int { return a }
+
+func newJSONRPCEngine(t *testing.T) (*Engine, *int) {
+	registry := NewJSONRPCRegistry()
+	if err := registry.Register(Arith{}); err != nil {
+		t.Fatal(err)
+	}
+	notified := 0
+	err := registry.RegisterFunc("notify", func(c *Context, msg string) (interface{}, error) {
+		notified++
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.RegisterFunc("teapot", func(c *Context) (interface{}, error) {
+		return nil, NewJSONRPCError(418, "I'm a teapot", H{"tea": "green"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := New()
+	r.Use(func(c *Context) { // 鉴权中间件对 JSON-RPC 同样生效
+		if c.Req.Header.Get("X-User") == "" {
+			c.Fail(http.StatusUnauthorized, "unauthorized")
+		}
+	})
+	r.POST("/rpc", JSONRPC(registry))
+	return r, &notified
+}
+
+func postRPC(r *Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/rpc", strings.NewReader(body))
+	req.Header.Set("X-User", "geektutu")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func assertJSONEqual(t *testing.T, got string, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal([]byte(got), &g); err != nil {
+		t.Fatalf("invalid JSON %q: %v", got, err)
+	}
+	_ = json.Unmarshal([]byte(want), &w)
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if string(gb) != string(wb) {
+		t.Fatalf("got %s, want %s", gb, wb)
+	}
+}
+
+func TestJSONRPC(t *testing.T) {
+	r, _ := newJSONRPCEngine(t)
+	cases := []struct {
+		name, body, want string
+	}{
+		{"named params", `{"jsonrpc":"2.0","method":"Arith.Add","params":{"a":1,"b":2},"id":1}`,
+			`{"jsonrpc":"2.0","result":3,"id":1}`},
+		{"positional params", `{"jsonrpc":"2.0","method":"Arith.Add","params":[4,5],"id":"x"}`,
+			`{"jsonrpc":"2.0","result":9,"id":"x"}`},
+		{"context.Context", `{"jsonrpc":"2.0","method":"Arith.Div","params":{"a":1,"b":4},"id":2}`,
+			`{"jsonrpc":"2.0","result":0.25,"id":2}`},
+		{"gee.Context", `{"jsonrpc":"2.0","method":"Arith.Whoami","id":3}`,
+			`{"jsonrpc":"2.0","result":"geektutu","id":3}`},
+		{"null id", `{"jsonrpc":"2.0","method":"Arith.Whoami","id":null}`,
+			`{"jsonrpc":"2.0","result":"geektutu","id":null}`},
+		{"application error", `{"jsonrpc":"2.0","method":"Arith.Div","params":[1,0],"id":4}`,
+			`{"jsonrpc":"2.0","error":{"code":-32000,"message":"division by zero"},"id":4}`},
+		{"custom error", `{"jsonrpc":"2.0","method":"teapot","id":5}`,
+			`{"jsonrpc":"2.0","error":{"code":418,"message":"I'm a teapot","data":{"tea":"green"}},"id":5}`},
+		{"method not found", `{"jsonrpc":"2.0","method":"Arith.Ignored","id":6}`,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found","data":"Arith.Ignored"},"id":6}`},
+		{"unknown field", `{"jsonrpc":"2.0","method":"Arith.Add","params":{"c":1},"id":7}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params","data":"json: unknown field \"c\""},"id":7}`},
+		{"too many params", `{"jsonrpc":"2.0","method":"Arith.Add","params":[1,2,3],"id":8}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params","data":"too many params: expected at most 2, got 3"},"id":8}`},
+		{"panic", `{"jsonrpc":"2.0","method":"Arith.Panic","id":9}`,
+			`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":9}`},
+		{"parse error", `{"jsonrpc":"2.0","method"`,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`},
+		{"invalid request", `{"jsonrpc":"1.0","method":"Arith.Add","id":1}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`},
+		{"empty batch", `[]`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request","data":"empty batch"},"id":null}`},
+		{"batch", `[
+			{"jsonrpc":"2.0","method":"Arith.Add","params":[1,1],"id":1},
+			{"jsonrpc":"2.0","method":"notify","params":["hi"]},
+			1,
+			{"jsonrpc":"2.0","method":"missing","id":2}
+		]`, `[
+			{"jsonrpc":"2.0","result":2,"id":1},
+			{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null},
+			{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found","data":"missing"},"id":2}
+		]`},
+	}
+	for _, tc := range cases {
+		w := postRPC(r, tc.body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d", tc.name, w.Code)
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			assertJSONEqual(t, w.Body.String(), tc.want)
+		})
+	}
+}
+
+func TestJSONRPCNotification(t *testing.T) {
+	r, notified := newJSONRPCEngine(t)
+
+	w := postRPC(r, `{"jsonrpc":"2.0","method":"notify","params":["hi"]}`)
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Fatalf("notification should have no response, got %d %q", w.Code, w.Body.String())
+	}
+	w = postRPC(r, `[{"jsonrpc":"2.0","method":"notify","params":["a"]},{"jsonrpc":"2.0","method":"notify","params":["b"]}]`)
+	if w.Code != http.StatusNoContent || *notified != 3 {
+		t.Fatalf("batch of notifications: got %d, notified %d", w.Code, *notified)
+	}
+}
+
+func TestJSONRPCMiddleware(t *testing.T) {
+	r, _ := newJSONRPCEngine(t)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"Arith.Whoami","id":1}`)))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestJSONRPCRegisterErrors(t *testing.T) {
+	registry := NewJSONRPCRegistry()
+	if err := registry.Register(struct{}{}); err == nil {
+		t.Fatal("expected error for type without methods")
+	}
+	if err := registry.RegisterFunc("bad", func(a int) (int, error) { return a, nil }); err == nil {
+		t.Fatal("expected error for function without ctx")
+	}
+	if err := registry.Register(&Arith{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(registry.Methods(), ","); got != "Arith.Add,Arith.Div,Arith.Panic,Arith.Whoami" {
+		t.Fatalf("unexpected methods %s", got)
+	}
+}
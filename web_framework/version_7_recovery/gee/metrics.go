@@ -0,0 +1,223 @@
+package gee
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLatencyBuckets 请求耗时直方图默认的桶（秒），与 Prometheus 客户端的默认值一致
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets 响应大小直方图默认的桶（字节）
+var DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// DefaultMetricsRegistry Metrics 和 MetricsHandler 使用的默认指标集合
+var DefaultMetricsRegistry = NewMetricsRegistry(DefaultLatencyBuckets, DefaultSizeBuckets)
+
+// Metrics 使用默认指标集合记录请求指标，例如 r.Use(gee.Metrics())
+func Metrics() HandlerFunc {
+	return DefaultMetricsRegistry.Middleware()
+}
+
+// MetricsHandler 以 Prometheus 文本格式输出默认指标集合，例如 r.GET("/metrics", gee.MetricsHandler())
+func MetricsHandler() HandlerFunc {
+	return DefaultMetricsRegistry.Handler()
+}
+
+// metricLabels 指标的标签。route 使用路由地址（例如 /user/:id）而不是请求路径，status 使用状态码分类（例如 2xx），
+// 保证标签的取值个数有限
+type metricLabels struct {
+	method string
+	route  string
+	status string
+}
+
+// MetricsRegistry 保存请求数、处理中的请求数、请求耗时和响应大小等指标
+type MetricsRegistry struct {
+	mu        sync.Mutex
+	requests  map[metricLabels]uint64
+	durations map[metricLabels]*histogram
+	sizes     map[metricLabels]*histogram
+	inFlight  int64
+
+	latencyBuckets []float64
+	sizeBuckets    []float64
+}
+
+// NewMetricsRegistry 创建指标集合，latencyBuckets 和 sizeBuckets 分别为耗时（秒）和响应大小（字节）直方图的桶上界，需要升序排列
+func NewMetricsRegistry(latencyBuckets, sizeBuckets []float64) *MetricsRegistry {
+	return &MetricsRegistry{
+		requests:       make(map[metricLabels]uint64),
+		durations:      make(map[metricLabels]*histogram),
+		sizes:          make(map[metricLabels]*histogram),
+		latencyBuckets: latencyBuckets,
+		sizeBuckets:    sizeBuckets,
+	}
+}
+
+// Middleware 记录请求指标的中间件
+func (m *MetricsRegistry) Middleware() HandlerFunc {
+	return func(c *Context) {
+		start := time.Now()
+		atomic.AddInt64(&m.inFlight, 1)
+		defer atomic.AddInt64(&m.inFlight, -1)
+
+		// 在 defer 中记录，处理函数 panic、由外层的 Recovery 返回 500 的请求也会被统计
+		panicked := true
+		defer func() {
+			m.observe(c, time.Since(start), panicked)
+		}()
+		c.Next()
+		panicked = false
+	}
+}
+
+func (m *MetricsRegistry) observe(c *Context, elapsed time.Duration, panicked bool) {
+	status := c.ResponseStatus()
+	if status == 0 && panicked { // 还没有写入响应，外层的 Recovery 会返回 500
+		status = http.StatusInternalServerError
+	}
+	if status == 0 {
+		status = c.StatusCode
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	route := c.FullPath()
+	if route == "" { // 未匹配的请求统一归为一类，避免扫描器制造大量不同的路径
+		route = "unmatched"
+	}
+	labels := metricLabels{
+		method: metricMethod(c.Method),
+		route:  route,
+		status: strconv.Itoa(status/100) + "xx",
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[labels]++
+	if m.durations[labels] == nil {
+		m.durations[labels] = newHistogram(m.latencyBuckets)
+		m.sizes[labels] = newHistogram(m.sizeBuckets)
+	}
+	m.durations[labels].observe(elapsed.Seconds())
+	m.sizes[labels].observe(float64(c.ResponseSize()))
+}
+
+// metricMethod 非标准的请求方法统一记为 OTHER，避免标签的取值无限增长
+func metricMethod(method string) string {
+	for _, m := range anyMethods {
+		if method == m {
+			return method
+		}
+	}
+	return "OTHER"
+}
+
+// Handler 以 Prometheus 文本格式（text/plain; version=0.0.4）输出指标
+func (m *MetricsRegistry) Handler() HandlerFunc {
+	return func(c *Context) {
+		c.SetHeader("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(http.StatusOK)
+		m.WriteText(c.Writer)
+	}
+}
+
+// WriteText 以 Prometheus 文本格式输出指标，同一指标的序列按标签排序，保证输出稳定
+func (m *MetricsRegistry) WriteText(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]metricLabels, 0, len(m.requests))
+	for labels := range m.requests {
+		keys = append(keys, labels)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		return a.status < b.status
+	})
+
+	fmt.Fprintln(w, "# HELP gee_http_requests_total Total number of HTTP requests.")
+	fmt.Fprintln(w, "# TYPE gee_http_requests_total counter")
+	for _, labels := range keys {
+		fmt.Fprintf(w, "gee_http_requests_total{%s} %d\n", labels.format(), m.requests[labels])
+	}
+
+	fmt.Fprintln(w, "# HELP gee_http_requests_in_flight Number of HTTP requests currently being served.")
+	fmt.Fprintln(w, "# TYPE gee_http_requests_in_flight gauge")
+	fmt.Fprintf(w, "gee_http_requests_in_flight %d\n", atomic.LoadInt64(&m.inFlight))
+
+	fmt.Fprintln(w, "# HELP gee_http_request_duration_seconds HTTP request latency in seconds.")
+	fmt.Fprintln(w, "# TYPE gee_http_request_duration_seconds histogram")
+	for _, labels := range keys {
+		m.durations[labels].write(w, "gee_http_request_duration_seconds", labels.format())
+	}
+
+	fmt.Fprintln(w, "# HELP gee_http_response_size_bytes HTTP response body size in bytes.")
+	fmt.Fprintln(w, "# TYPE gee_http_response_size_bytes histogram")
+	for _, labels := range keys {
+		m.sizes[labels].write(w, "gee_http_response_size_bytes", labels.format())
+	}
+}
+
+func (l metricLabels) format() string {
+	return fmt.Sprintf(`method="%s",route="%s",status="%s"`,
+		escapeLabel(l.method), escapeLabel(l.route), escapeLabel(l.status))
+}
+
+// escapeLabel 按文本格式的要求转义标签值中的反斜杠、双引号和换行
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// histogram 直方图，counts[i] 为落在 (buckets[i-1], buckets[i]] 中的观测次数，最后一个元素为大于所有桶上界的次数
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v) // 第一个 >= v 的桶
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// write 输出直方图，桶的计数是累计值，最后输出 le="+Inf"、_sum 和 _count
+func (h *histogram) write(w io.Writer, name string, labels string) {
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
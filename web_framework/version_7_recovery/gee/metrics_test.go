@@ -0,0 +1,205 @@
+package gee
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// promSample 文本格式中的一个样本
+type promSample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// parsePrometheus 解析 Prometheus 文本格式，校验每个样本都有对应的 TYPE 声明，返回所有样本
+func parsePrometheus(text string) ([]promSample, map[string]string, error) {
+	var samples []promSample
+	types := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "# HELP ") {
+			continue
+		}
+		if strings.HasPrefix(line, "# TYPE ") {
+			fields := strings.Fields(line)
+			if len(fields) != 4 {
+				return nil, nil, fmt.Errorf("invalid TYPE line %q", line)
+			}
+			types[fields[2]] = fields[3]
+			continue
+		}
+
+		sample := promSample{labels: make(map[string]string)}
+		rest := line
+		if i := strings.IndexByte(line, '{'); i >= 0 {
+			sample.name = line[:i]
+			j := strings.LastIndexByte(line, '}')
+			if j < i {
+				return nil, nil, fmt.Errorf("unclosed labels in %q", line)
+			}
+			for _, pair := range splitLabels(line[i+1 : j]) {
+				kv := strings.SplitN(pair, "=", 2)
+				if len(kv) != 2 || len(kv[1]) < 2 || kv[1][0] != '"' || kv[1][len(kv[1])-1] != '"' {
+					return nil, nil, fmt.Errorf("invalid label %q in %q", pair, line)
+				}
+				value, err := strconv.Unquote(kv[1])
+				if err != nil {
+					return nil, nil, err
+				}
+				sample.labels[kv[0]] = value
+			}
+			rest = line[j+1:]
+		} else {
+			fields := strings.Fields(line)
+			sample.name, rest = fields[0], strings.TrimPrefix(line, fields[0])
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(rest), 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid value in %q: %v", line, err)
+		}
+		sample.value = value
+
+		family := sample.name
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			if base := strings.TrimSuffix(family, suffix); base != family && types[base] == "histogram" {
+				family = base
+			}
+		}
+		if _, ok := types[family]; !ok {
+			return nil, nil, fmt.Errorf("sample %q has no TYPE", sample.name)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, types, scanner.Err()
+}
+
+// splitLabels 按逗号分割标签，忽略引号中的逗号
+func splitLabels(s string) []string {
+	var parts []string
+	inQuote, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			inQuote = !inQuote
+		case ',':
+			if !inQuote {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func findSample(samples []promSample, name string, labels map[string]string) (float64, bool) {
+	for _, s := range samples {
+		if s.name != name {
+			continue
+		}
+		match := true
+		for k, v := range labels {
+			if s.labels[k] != v {
+				match = false
+				break
+			}
+		}
+		if match {
+			return s.value, true
+		}
+	}
+	return 0, false
+}
+
+func TestMetrics(t *testing.T) {
+	m := NewMetricsRegistry(DefaultLatencyBuckets, DefaultSizeBuckets)
+	r := New()
+	r.Use(m.Middleware())
+	r.GET("/user/:id", func(c *Context) {
+		c.String(http.StatusOK, "user %s", c.Param("id"))
+	})
+	r.GET("/metrics", m.Handler())
+
+	for _, path := range []string{"/user/1", "/user/2", "/user/3", "/nope"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	samples, types, err := parsePrometheus(w.Body.String())
+	if err != nil {
+		t.Fatalf("%v\n%s", err, w.Body.String())
+	}
+	if types["gee_http_requests_total"] != "counter" || types["gee_http_request_duration_seconds"] != "histogram" {
+		t.Fatalf("unexpected types %v", types)
+	}
+
+	user := map[string]string{"method": "GET", "route": "/user/:id", "status": "2xx"}
+	if v, _ := findSample(samples, "gee_http_requests_total", user); v != 3 {
+		t.Fatalf("expect 3 requests for /user/:id, got %v", v)
+	}
+	if v, _ := findSample(samples, "gee_http_requests_total", map[string]string{"route": "unmatched"}); v != 1 {
+		t.Fatalf("expect 1 unmatched request, got %v", v)
+	}
+	if v, ok := findSample(samples, "gee_http_requests_in_flight", nil); !ok || v != 1 { // 正在处理 /metrics 请求本身
+		t.Fatalf("expect 1 in-flight request, got %v", v)
+	}
+	if v, _ := findSample(samples, "gee_http_response_size_bytes_sum", user); v != float64(len("user 1")*3) {
+		t.Fatalf("unexpected response size sum %v", v)
+	}
+
+	// 直方图的桶是累计值，且 +Inf 桶等于 _count
+	prev := -1.0
+	for _, s := range samples {
+		if s.name != "gee_http_request_duration_seconds_bucket" || s.labels["route"] != "/user/:id" {
+			continue
+		}
+		if s.value < prev {
+			t.Fatalf("bucket counts must be cumulative: %v", s)
+		}
+		prev = s.value
+	}
+	inf, _ := findSample(samples, "gee_http_request_duration_seconds_bucket", map[string]string{"route": "/user/:id", "le": "+Inf"})
+	count, _ := findSample(samples, "gee_http_request_duration_seconds_count", user)
+	if inf != 3 || count != 3 {
+		t.Fatalf("expect +Inf bucket and count to be 3, got %v and %v", inf, count)
+	}
+}
+
+func TestMetricsPanic(t *testing.T) {
+	m := NewMetricsRegistry(DefaultLatencyBuckets, DefaultSizeBuckets)
+	r := New()
+	r.Use(Recovery(), m.Middleware()) // Recovery 在外层
+	r.GET("/panic", func(c *Context) {
+		panic("boom")
+	})
+	r.GET("/metrics", m.Handler())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	samples, _, err := parsePrometheus(w.Body.String())
+	if err != nil {
+		t.Fatalf("%v\n%s", err, w.Body.String())
+	}
+	labels := map[string]string{"method": "GET", "route": "/panic", "status": "5xx"}
+	if v, _ := findSample(samples, "gee_http_requests_total", labels); v != 1 {
+		t.Fatalf("expected the panicking request to be counted as 5xx, got %v\n%s", v, w.Body.String())
+	}
+	if v, ok := findSample(samples, "gee_http_requests_in_flight", nil); !ok || v != 1 {
+		t.Fatalf("expect 1 in-flight request, got %v", v)
+	}
+}
@@ -0,0 +1,57 @@
+package gee
+
+import (
+	"log"
+	"os"
+	"sync/atomic"
+)
+
+const (
+	// DebugMode 开发模式，例如 panic 时返回带有调用栈和源码的 HTML 页面
+	DebugMode = "debug"
+	// ReleaseMode 生产模式，不向客户端暴露任何内部信息
+	ReleaseMode = "release"
+)
+
+// EnvGeeMode 启动时从该环境变量读取运行模式，例如 GEE_MODE=debug
+const EnvGeeMode = "GEE_MODE"
+
+var geeMode atomic.Value
+
+func init() {
+	setModeFromEnv(os.Getenv(EnvGeeMode))
+}
+
+// setModeFromEnv 按环境变量设置运行模式。与 SetMode 不同，未知的值不会 panic，而是打印警告并使用 ReleaseMode，
+// 避免配置写错时在生产环境中向客户端暴露调用栈和源码
+func setModeFromEnv(value string) {
+	switch value {
+	case "", DebugMode, ReleaseMode:
+		SetMode(value)
+	default:
+		log.Printf("gee: unknown %s=%q, available modes: debug, release, falling back to release", EnvGeeMode, value)
+		SetMode(ReleaseMode)
+	}
+}
+
+// SetMode 设置运行模式，空字符串表示 ReleaseMode，其他未知的值会 panic
+func SetMode(mode string) {
+	switch mode {
+	case "":
+		mode = ReleaseMode
+	case DebugMode, ReleaseMode:
+	default:
+		panic("gee: unknown mode " + mode + ", available modes: debug, release")
+	}
+	geeMode.Store(mode)
+}
+
+// Mode 返回当前的运行模式
+func Mode() string {
+	return geeMode.Load().(string)
+}
+
+// IsDebugging 是否处于开发模式
+func IsDebugging() bool {
+	return Mode() == DebugMode
+}
@@ -0,0 +1,24 @@
+package gee
+
+import "testing"
+
+func TestSetModeFromEnv(t *testing.T) {
+	defer SetMode(Mode())
+
+	setModeFromEnv("")
+	if Mode() != ReleaseMode {
+		t.Fatalf("empty GEE_MODE should be release, got %s", Mode())
+	}
+	setModeFromEnv(DebugMode)
+	setModeFromEnv("prod") // 未知的值不 panic，使用 release，不暴露调用栈
+	if Mode() != ReleaseMode {
+		t.Fatalf("unknown GEE_MODE should fall back to release, got %s", Mode())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("SetMode with an unknown mode should panic")
+		}
+	}()
+	SetMode("prod")
+}
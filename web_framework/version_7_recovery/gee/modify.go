@@ -0,0 +1,177 @@
+package gee
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// DefaultModifyResponseLimit ModifyResponse 默认最多缓冲的响应体大小
+const DefaultModifyResponseLimit = 1 << 20
+
+// BufferedResponse 被缓冲的响应，ModifyResponse 的回调可以在发送给客户端之前修改状态码、响应头和响应体
+type BufferedResponse struct {
+	Status int
+	Header http.Header // 即将发送的响应头，修改会直接生效，Content-Length 会按修改后的 Body 重新计算
+	Body   []byte
+}
+
+// ContentType 返回不含参数的 Content-Type，例如 application/json
+func (r *BufferedResponse) ContentType() string {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// DecodeJSON 将 JSON 响应体解码到 v
+func (r *BufferedResponse) DecodeJSON(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// SetJSON 将 v 编码为 JSON 作为新的响应体，并设置 Content-Type
+func (r *BufferedResponse) SetJSON(v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Body = body
+	return nil
+}
+
+// ModifyResponse 缓冲处理函数写入的响应，在发送给客户端之前交给 fn 修改，最多缓冲 DefaultModifyResponseLimit 字节
+func ModifyResponse(fn func(c *Context, resp *BufferedResponse) error) HandlerFunc {
+	return ModifyResponseWithLimit(DefaultModifyResponseLimit, fn)
+}
+
+// ModifyResponseWithLimit 缓冲处理函数写入的响应，在发送给客户端之前交给 fn 修改，例如改写 JSON 结构、向 HTML 插入片段、为响应体签名。
+// 以下响应不经过 fn，原样发送：
+//   - 响应体超过 limit 字节，超过时已缓冲的内容和之后写入的内容直接发送；
+//   - 处理函数调用了 Flush（例如 SSE）或 Hijack（例如 WebSocket）；
+//   - 已经压缩过的响应，即设置了 Content-Encoding。
+//
+// fn 返回错误时丢弃原响应，返回 500
+func ModifyResponseWithLimit(limit int, fn func(c *Context, resp *BufferedResponse) error) HandlerFunc {
+	return func(c *Context) {
+		writer := c.Writer
+		buffer := &bufferedWriter{ResponseWriter: writer, limit: limit}
+		c.Writer = buffer
+		defer func() {
+			c.Writer = writer
+		}()
+		c.Next()
+		c.Writer = writer
+
+		if buffer.hijacked || buffer.passthrough {
+			return
+		}
+		status := buffer.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		resp := &BufferedResponse{Status: status, Header: writer.Header(), Body: buffer.body.Bytes()}
+		if resp.Header.Get("Content-Encoding") == "" {
+			if err := fn(c, resp); err != nil {
+				c.Error(fmt.Errorf("gee: modify response: %w", err))
+				resp.Header.Del("Content-Length")
+				resp.Header.Del("Content-Encoding")
+				c.Fail(http.StatusInternalServerError, "Internal Server Error")
+				return
+			}
+		}
+		if len(resp.Body) > 0 || resp.Header.Get("Content-Length") != "" {
+			resp.Header.Set("Content-Length", strconv.Itoa(len(resp.Body)))
+		}
+		writer.WriteHeader(resp.Status)
+		_, _ = writer.Write(resp.Body)
+	}
+}
+
+// bufferedWriter 缓冲响应体，超过限制、Flush 或 Hijack 后切换为直接写入底层的 ResponseWriter
+type bufferedWriter struct {
+	http.ResponseWriter
+	limit       int
+	status      int
+	body        bytes.Buffer
+	passthrough bool // 不再缓冲，直接写入
+	hijacked    bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.body.Len()+len(data) > w.limit {
+		if err := w.startPassthrough(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+// startPassthrough 发送已缓冲的状态码和响应体，之后的写入直接发送
+func (w *bufferedWriter) startPassthrough() error {
+	w.passthrough = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.body.Len() > 0 {
+		if _, err := w.ResponseWriter.Write(w.body.Bytes()); err != nil {
+			return err
+		}
+	}
+	w.body.Reset()
+	return nil
+}
+
+// Flush 流式响应不再缓冲，立即发送已写入的内容
+func (w *bufferedWriter) Flush() {
+	if !w.passthrough {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		if err := w.startPassthrough(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack 接管连接后不再缓冲，也不再修改响应
+func (w *bufferedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("gee: response writer does not implement http.Hijacker")
+	}
+	w.hijacked = true
+	w.passthrough = true
+	return hijacker.Hijack()
+}
+
+// Unwrap 返回底层的 http.ResponseWriter，供 http.ResponseController 使用
+func (w *bufferedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,106 @@
+package gee
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestModifyResponse(t *testing.T) {
+	r := New()
+	r.Use(ModifyResponseWithLimit(64, func(c *Context, resp *BufferedResponse) error {
+		switch resp.ContentType() {
+		case "application/json": // 包装为统一的结构，并去掉敏感字段
+			var data map[string]interface{}
+			if err := resp.DecodeJSON(&data); err != nil {
+				return err
+			}
+			delete(data, "password")
+			return resp.SetJSON(H{"code": resp.Status, "data": data})
+		case "text/html":
+			resp.Body = bytes.Replace(resp.Body, []byte("</body>"), []byte("<script></script></body>"), 1)
+		case "text/plain":
+			if string(resp.Body) == "bad" {
+				return errors.New("bad body")
+			}
+			resp.Header.Set("X-Signature", "signed")
+		}
+		return nil
+	}))
+	r.GET("/user", func(c *Context) {
+		c.JSON(http.StatusCreated, H{"name": "geektutu", "password": "123"})
+	})
+	r.GET("/page", func(c *Context) {
+		c.SetHeader("Content-Type", "text/html")
+		c.SetHeader("Content-Length", "26")
+		c.Data(http.StatusOK, []byte("<html><body></body></html>"))
+	})
+	r.GET("/large", func(c *Context) {
+		c.String(http.StatusOK, strings.Repeat("a", 100))
+	})
+	r.GET("/bad", func(c *Context) {
+		c.String(http.StatusOK, "bad")
+	})
+	r.GET("/stream", func(c *Context) {
+		c.String(http.StatusOK, "chunk")
+		c.Writer.(http.Flusher).Flush()
+	})
+
+	cases := []struct {
+		path, body, signature string
+		code                  int
+	}{
+		{"/user", `{"code":201,"data":{"name":"geektutu"}}`, "", http.StatusCreated},
+		{"/page", "<html><body><script></script></body></html>", "", http.StatusOK},
+		{"/large", strings.Repeat("a", 100), "", http.StatusOK}, // 超过限制，原样发送
+		{"/bad", `{"message":"Internal Server Error"}`, "", http.StatusInternalServerError},
+		{"/stream", "chunk", "", http.StatusOK}, // 流式响应不修改
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+		if w.Code != tc.code || strings.TrimSpace(w.Body.String()) != tc.body {
+			t.Fatalf("%s: expected %d %s, got %d %s", tc.path, tc.code, tc.body, w.Code, w.Body.String())
+		}
+		if w.Header().Get("X-Signature") != "" {
+			t.Fatalf("%s: text response should not be signed", tc.path)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/page", nil))
+	if w.Header().Get("Content-Length") != "43" {
+		t.Fatalf("Content-Length should match the modified body, got %s", w.Header().Get("Content-Length"))
+	}
+}
+
+func TestModifyResponseSigned(t *testing.T) {
+	r := New()
+	r.Use(ModifyResponse(func(c *Context, resp *BufferedResponse) error {
+		resp.Header.Set("X-Signature", "signed")
+		resp.Status = http.StatusAccepted
+		return nil
+	}))
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/gzip", func(c *Context) {
+		c.SetHeader("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, []byte{0x1f, 0x8b})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusAccepted || w.Header().Get("X-Signature") != "signed" || w.Body.String() != "ok" {
+		t.Fatalf("unexpected response %d %v %s", w.Code, w.Header(), w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/gzip", nil))
+	if w.Code != http.StatusOK || w.Header().Get("X-Signature") != "" {
+		t.Fatalf("compressed response should not be modified, got %d %v", w.Code, w.Header())
+	}
+}
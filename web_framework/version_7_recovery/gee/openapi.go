@@ -0,0 +1,767 @@
+package gee
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIInfo OpenAPI 文档的基本信息
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+}
+
+// OpenAPIDocument 生成的 OpenAPI 3.1 文档，内部以 map 和 slice 组成的树保存，便于同时输出 JSON 和 YAML
+type OpenAPIDocument struct {
+	tree map[string]interface{}
+}
+
+// JSON 输出 JSON 格式的文档，对象的键按字典序排列，保证输出稳定
+func (doc *OpenAPIDocument) JSON() ([]byte, error) {
+	return json.MarshalIndent(doc.tree, "", "  ")
+}
+
+// YAML 输出 YAML 格式的文档，对象的键按字典序排列，保证输出稳定
+func (doc *OpenAPIDocument) YAML() ([]byte, error) {
+	var b strings.Builder
+	writeYAML(&b, doc.tree, 0)
+	return []byte(b.String()), nil
+}
+
+// OpenAPIHandler 返回提供 OpenAPI 文档的处理函数，注册在哪个路由下，文档就在哪个地址提供，例如：
+//
+//	r.GET("/openapi.json", gee.OpenAPIHandler(r, gee.OpenAPIInfo{Title: "demo", Version: "1.0"}))
+//	r.GET("/openapi.yaml", gee.OpenAPIHandler(r, gee.OpenAPIInfo{Title: "demo", Version: "1.0"}))
+//
+// 请求路径以 .yaml 或 .yml 结尾，或者 Accept 中包含 yaml 时输出 YAML，否则输出 JSON。
+// 每次请求时重新遍历路由生成文档，因此之后注册的路由也会出现在文档中。
+func OpenAPIHandler(engine *Engine, info OpenAPIInfo) HandlerFunc {
+	return func(c *Context) {
+		doc := GenerateOpenAPI(engine, info)
+		if strings.HasSuffix(c.Path, ".yaml") || strings.HasSuffix(c.Path, ".yml") ||
+			strings.Contains(c.Req.Header.Get("Accept"), "yaml") {
+			data, _ := doc.YAML()
+			c.SetHeader("Content-Type", "application/yaml")
+			c.Data(http.StatusOK, data)
+			return
+		}
+		data, err := doc.JSON()
+		if err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.SetHeader("Content-Type", "application/json")
+		c.Data(http.StatusOK, data)
+	}
+}
+
+// openAPIMethods OpenAPI 支持的请求方法，CONNECT 不在其中
+var openAPIMethods = map[string]string{
+	http.MethodGet:     "get",
+	http.MethodPost:    "post",
+	http.MethodPut:     "put",
+	http.MethodPatch:   "patch",
+	http.MethodDelete:  "delete",
+	http.MethodHead:    "head",
+	http.MethodOptions: "options",
+	http.MethodTrace:   "trace",
+}
+
+// GenerateOpenAPI 遍历 engine 中注册的路由，生成 OpenAPI 3.1 文档。
+// 不同 API 版本或不同 Host 下相同方法和路径的路由合并为一个操作，见 mergeOperations
+func GenerateOpenAPI(engine *Engine, info OpenAPIInfo) *OpenAPIDocument {
+	g := &openAPIGenerator{
+		schemas: make(map[string]interface{}),
+		names:   make(map[reflect.Type]string),
+		types:   make(map[string]reflect.Type),
+	}
+
+	// 先按路径和方法分组，保持路由注册的顺序
+	type operationKey struct{ path, method string }
+	var keys []operationKey
+	groups := make(map[operationKey][]RouteInfo)
+	for _, route := range engine.Routes() {
+		method, ok := openAPIMethods[route.Method]
+		if !ok {
+			continue
+		}
+		path, _ := openAPIPath(route.Pattern)
+		key := operationKey{path, method}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], route)
+	}
+
+	versionHeader := engine.versioning.Header
+	if versionHeader == "" {
+		versionHeader = "Api-Version"
+	}
+	paths := make(map[string]interface{})
+	for _, key := range keys {
+		var ops []map[string]interface{}
+		var versions []interface{}
+		for _, route := range groups[key] {
+			_, pathParams := openAPIPath(route.Pattern)
+			ops = append(ops, g.operation(route, pathParams))
+			if route.Version != "" && !containsValue(versions, route.Version) {
+				versions = append(versions, route.Version)
+			}
+		}
+		op := mergeOperations(ops)
+		if len(versions) > 0 { // 分版本的路由通过请求头选择版本
+			op["parameters"] = appendParam(op["parameters"], map[string]interface{}{
+				"name":   versionHeader,
+				"in":     "header",
+				"schema": map[string]interface{}{"type": "string", "enum": versions},
+			})
+		}
+		item, ok := paths[key.path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[key.path] = item
+		}
+		item[key.method] = op
+	}
+
+	docInfo := map[string]interface{}{
+		"title":   info.Title,
+		"version": info.Version,
+	}
+	if info.Description != "" {
+		docInfo["description"] = info.Description
+	}
+	tree := map[string]interface{}{
+		"openapi": "3.1.0",
+		"info":    docInfo,
+		"paths":   paths,
+	}
+	if len(g.schemas) > 0 {
+		tree["components"] = map[string]interface{}{"schemas": g.schemas}
+	}
+	return &OpenAPIDocument{tree: tree}
+}
+
+// openAPIPath 将路由地址转换为 OpenAPI 的路径模板，例如 /user/:id<int> 转换为 /user/{id}，同时返回路径参数
+func openAPIPath(pattern string) (string, []map[string]interface{}) {
+	var params []map[string]interface{}
+	parts := parsePath(pattern)
+	for i, part := range parts {
+		if part == "" || (part[0] != ':' && part[0] != '*') {
+			continue
+		}
+		name, constraint, err := parseParam(part)
+		if err != nil {
+			continue
+		}
+		parts[i] = "{" + name + "}"
+		params = append(params, map[string]interface{}{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   constraintSchema(constraint),
+		})
+	}
+	return "/" + strings.Join(parts, "/"), params
+}
+
+// constraintSchema 根据路由参数约束推断参数的类型
+func constraintSchema(constraint string) map[string]interface{} {
+	switch constraint {
+	case "":
+		return map[string]interface{}{"type": "string"}
+	case "int", "uint":
+		return map[string]interface{}{"type": "integer"}
+	case "uuid":
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	}
+	if expr, ok := paramConstraints[constraint]; ok {
+		constraint = expr
+	}
+	return map[string]interface{}{"type": "string", "pattern": "^(?:" + constraint + ")$"}
+}
+
+type openAPIGenerator struct {
+	schemas map[string]interface{}  // components/schemas，具名结构体只生成一次，其余地方通过 $ref 引用
+	names   map[reflect.Type]string // 结构体在 components/schemas 中的名称
+	types   map[string]reflect.Type // 已使用的名称，用于发现不同包中的同名结构体
+}
+
+// mergeOperations 合并相同方法和路径的多个操作，例如不同版本的同一个接口：
+// 摘要等信息取第一个非空的值，标签和参数取并集，请求体和同一状态码的响应不同时用 oneOf 列出所有可能
+func mergeOperations(ops []map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for key, value := range ops[0] {
+		merged[key] = value
+	}
+	for _, op := range ops[1:] {
+		for _, key := range []string{"operationId", "summary", "description"} {
+			if _, ok := merged[key]; !ok && op[key] != nil {
+				merged[key] = op[key]
+			}
+		}
+		if tags, ok := op["tags"].([]string); ok {
+			existing, _ := merged["tags"].([]string)
+			existing = append([]string(nil), existing...) // 不修改路由中的切片
+			for _, tag := range tags {
+				if !containsString(existing, tag) {
+					existing = append(existing, tag)
+				}
+			}
+			merged["tags"] = existing
+		}
+		if params, ok := op["parameters"].([]interface{}); ok {
+			for _, param := range params {
+				merged["parameters"] = appendParam(merged["parameters"], param.(map[string]interface{}))
+			}
+		}
+		if body, ok := op["requestBody"].(map[string]interface{}); ok {
+			merged["requestBody"] = mergeContent(merged["requestBody"], body)
+		}
+		responses := make(map[string]interface{})
+		for code, resp := range merged["responses"].(map[string]interface{}) {
+			responses[code] = resp
+		}
+		for code, resp := range op["responses"].(map[string]interface{}) {
+			responses[code] = mergeContent(responses[code], resp.(map[string]interface{}))
+		}
+		merged["responses"] = responses
+	}
+	return merged
+}
+
+// appendParam 添加参数，已有同名同位置的参数时忽略
+func appendParam(params interface{}, param map[string]interface{}) []interface{} {
+	list, _ := params.([]interface{})
+	for _, p := range list {
+		p := p.(map[string]interface{})
+		if p["in"] == param["in"] && p["name"] == param["name"] {
+			return list
+		}
+	}
+	return append(list, param)
+}
+
+// mergeContent 合并请求体或响应的内容，同一媒体类型的 schema 不同时用 oneOf 列出
+func mergeContent(existing interface{}, next map[string]interface{}) map[string]interface{} {
+	prev, ok := existing.(map[string]interface{})
+	if !ok {
+		return next
+	}
+	nextContent, _ := next["content"].(map[string]interface{})
+	if len(nextContent) == 0 {
+		return prev
+	}
+	content := make(map[string]interface{})
+	if prevContent, ok := prev["content"].(map[string]interface{}); ok {
+		for mediaType, media := range prevContent {
+			content[mediaType] = media
+		}
+	}
+	for mediaType, media := range nextContent {
+		content[mediaType] = mergeMedia(content[mediaType], media.(map[string]interface{}))
+	}
+	merged := make(map[string]interface{})
+	for key, value := range prev {
+		merged[key] = value
+	}
+	merged["content"] = content
+	return merged
+}
+
+// mergeMedia 合并同一媒体类型的 schema
+func mergeMedia(existing interface{}, next map[string]interface{}) map[string]interface{} {
+	prev, ok := existing.(map[string]interface{})
+	if !ok {
+		return next
+	}
+	prevSchema, _ := prev["schema"].(map[string]interface{})
+	nextSchema, _ := next["schema"].(map[string]interface{})
+	var schemas []interface{}
+	if oneOf, ok := prevSchema["oneOf"].([]interface{}); ok {
+		schemas = append(schemas, oneOf...)
+	} else {
+		schemas = append(schemas, prevSchema)
+	}
+	for _, schema := range schemas {
+		if reflect.DeepEqual(schema, nextSchema) {
+			return prev
+		}
+	}
+	return map[string]interface{}{"schema": map[string]interface{}{"oneOf": append(schemas, nextSchema)}}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsValue(list []interface{}, v interface{}) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *openAPIGenerator) operation(route RouteInfo, pathParams []map[string]interface{}) map[string]interface{} {
+	op := make(map[string]interface{})
+	if route.Name != "" {
+		op["operationId"] = route.Name
+	}
+	if route.Summary != "" {
+		op["summary"] = route.Summary
+	}
+	if route.Description != "" {
+		op["description"] = route.Description
+	}
+	if len(route.Tags) > 0 {
+		op["tags"] = route.Tags
+	}
+
+	params := pathParams
+	if route.Request != nil {
+		t := indirectType(reflect.TypeOf(route.Request))
+		if t.Kind() == reflect.Struct {
+			structParams, body, form := g.requestParams(t, formBodyMethods[route.Method])
+			params = mergeParams(params, structParams)
+			content := make(map[string]interface{})
+			if body != nil && route.Method != http.MethodGet && route.Method != http.MethodHead {
+				content["application/json"] = map[string]interface{}{"schema": body}
+			}
+			if form != nil {
+				content["application/x-www-form-urlencoded"] = map[string]interface{}{"schema": form}
+			}
+			if len(content) > 0 {
+				op["requestBody"] = map[string]interface{}{"required": true, "content": content}
+			}
+		}
+	}
+	if len(params) > 0 {
+		list := make([]interface{}, 0, len(params))
+		for _, p := range params {
+			list = append(list, p)
+		}
+		op["parameters"] = list
+	}
+
+	responses := make(map[string]interface{})
+	for code, v := range route.Responses {
+		resp := map[string]interface{}{"description": http.StatusText(code)}
+		if v != nil {
+			resp["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.schema(reflect.TypeOf(v))},
+			}
+		}
+		responses[strconv.Itoa(code)] = resp
+	}
+	if len(responses) == 0 {
+		responses["200"] = map[string]interface{}{"description": http.StatusText(http.StatusOK)}
+	}
+	op["responses"] = responses
+	return op
+}
+
+// formBodyMethods 表单字段从请求体读取的请求方法，与 http.Request.ParseForm 一致，其余方法的表单字段来自查询参数
+var formBodyMethods = map[string]bool{
+	http.MethodPost:  true,
+	http.MethodPut:   true,
+	http.MethodPatch: true,
+}
+
+// requestParams 解析请求结构体，uri、header 字段作为参数，只有 form 标签的字段在 formBody 为 true 时组成
+// application/x-www-form-urlencoded 请求体，否则作为查询参数，其余字段组成 JSON 请求体
+func (g *openAPIGenerator) requestParams(t reflect.Type, formBody bool) (params []map[string]interface{}, body, form map[string]interface{}) {
+	properties := make(map[string]interface{})
+	var required []interface{}
+	formProperties := make(map[string]interface{})
+	var formRequired []interface{}
+
+	for _, field := range structFields(t) {
+		rules := parseRules(field)
+		schema := g.schema(field.Type)
+		applyRules(schema, field.Type, rules)
+
+		// 同时有 form 和 json 标签的字段既可以来自表单也可以来自 JSON，按请求体处理
+		location, name := "", ""
+		if value := tagName(field, "uri"); value != "" {
+			location, name = "path", value
+		} else if value := tagName(field, "header"); value != "" {
+			location, name = "header", value
+		} else if value := tagName(field, "form"); value != "" && tagName(field, "json") == "" {
+			if formBody {
+				formProperties[value] = schema
+				if rules.has("required") {
+					formRequired = append(formRequired, value)
+				}
+				continue
+			}
+			location, name = "query", value
+		}
+		if location != "" {
+			param := map[string]interface{}{
+				"name":   name,
+				"in":     location,
+				"schema": schema,
+			}
+			if location == "path" || rules.has("required") {
+				param["required"] = true
+			}
+			params = append(params, param)
+			continue
+		}
+
+		name = jsonName(field)
+		if name == "" {
+			continue
+		}
+		properties[name] = schema
+		if rules.has("required") {
+			required = append(required, name)
+		}
+	}
+
+	return params, objectSchema(properties, required), objectSchema(formProperties, formRequired)
+}
+
+// objectSchema 由属性组成的对象，没有属性时返回 nil
+func objectSchema(properties map[string]interface{}, required []interface{}) map[string]interface{} {
+	if len(properties) == 0 {
+		return nil
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// mergeParams 合并路由中的路径参数和结构体中声明的参数，结构体中声明的同名参数优先
+func mergeParams(pathParams, structParams []map[string]interface{}) []map[string]interface{} {
+	declared := make(map[string]bool)
+	for _, p := range structParams {
+		declared[p["in"].(string)+":"+p["name"].(string)] = true
+	}
+	var params []map[string]interface{}
+	for _, p := range pathParams {
+		if !declared["path:"+p["name"].(string)] {
+			params = append(params, p)
+		}
+	}
+	return append(params, structParams...)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema 根据类型生成 JSON Schema，具名结构体放入 components/schemas 并返回引用
+func (g *openAPIGenerator) schema(t reflect.Type) map[string]interface{} {
+	t = indirectType(t)
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 { // []byte 按 base64 字符串编码
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" { // 匿名结构体直接展开
+			return g.structSchema(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.schemaName(t)
+			g.schemas[name] = map[string]interface{}{} // 先占位，防止递归类型无限展开
+			g.schemas[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{} // interface{} 等类型不做约束
+}
+
+// schemaName 结构体在 components/schemas 中的名称，以包名限定，例如 model.User。
+// 不同包的包名相同时，改用完整的包路径，例如 example.com_a_model.User
+func (g *openAPIGenerator) schemaName(t reflect.Type) string {
+	name := t.Name()
+	if pkg := t.PkgPath(); pkg != "" {
+		name = path.Base(pkg) + "." + t.Name()
+		if _, taken := g.types[name]; taken {
+			name = strings.NewReplacer("/", "_", "~", "_").Replace(pkg) + "." + t.Name()
+		}
+	}
+	g.names[t] = name
+	g.types[name] = t
+	return name
+}
+
+func (g *openAPIGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []interface{}
+	for _, field := range structFields(t) {
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+		rules := parseRules(field)
+		schema := g.schema(field.Type)
+		applyRules(schema, field.Type, rules)
+		properties[name] = schema
+		if rules.has("required") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// structFields 返回结构体的导出字段，匿名嵌入且没有 json 名称的结构体字段会被展开
+func structFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct && tagName(field, "json") == "" {
+			fields = append(fields, structFields(indirectType(field.Type))...)
+			continue
+		}
+		if field.PkgPath != "" { // 未导出的字段
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// tagName 获取标签中的名称部分，例如 `json:"name,omitempty"` 得到 "name"，"-" 视为没有名称
+func tagName(field reflect.StructField, key string) string {
+	name := strings.Split(field.Tag.Get(key), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// jsonName 字段在 JSON 中的名称，与 encoding/json 的规则一致
+func jsonName(field reflect.StructField) string {
+	if field.Tag.Get("json") == "-" {
+		return ""
+	}
+	if name := tagName(field, "json"); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// validationRules 字段的校验规则，来自 binding 和 validate 标签，例如 `binding:"required,min=1,max=10"`
+type validationRules map[string]string
+
+func parseRules(field reflect.StructField) validationRules {
+	rules := make(validationRules)
+	for _, key := range []string{"binding", "validate"} {
+		for _, rule := range strings.Split(field.Tag.Get(key), ",") {
+			if rule == "" {
+				continue
+			}
+			name, value := rule, ""
+			if i := strings.IndexByte(rule, '='); i >= 0 {
+				name, value = rule[:i], rule[i+1:]
+			}
+			rules[name] = value
+		}
+	}
+	return rules
+}
+
+func (rules validationRules) has(name string) bool {
+	_, ok := rules[name]
+	return ok
+}
+
+// applyRules 将校验规则转换为 JSON Schema 的约束，min/max 对数字约束取值，对字符串约束长度，对数组约束元素个数
+func applyRules(schema map[string]interface{}, t reflect.Type, rules validationRules) {
+	if _, ok := schema["$ref"]; ok { // 引用的 schema 不能附加约束
+		return
+	}
+	t = indirectType(t)
+	bound := func(rule string, keys [3]string) {
+		value, ok := rules[rule]
+		if !ok {
+			return
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return
+		}
+		switch schema["type"] {
+		case "integer", "number":
+			schema[keys[0]] = jsonNumber(n)
+		case "string":
+			schema[keys[1]] = jsonNumber(n)
+		case "array":
+			schema[keys[2]] = jsonNumber(n)
+		}
+	}
+	bound("min", [3]string{"minimum", "minLength", "minItems"})
+	bound("gte", [3]string{"minimum", "minLength", "minItems"})
+	bound("max", [3]string{"maximum", "maxLength", "maxItems"})
+	bound("lte", [3]string{"maximum", "maxLength", "maxItems"})
+	if rules.has("len") {
+		bound("len", [3]string{"minimum", "minLength", "minItems"})
+		bound("len", [3]string{"maximum", "maxLength", "maxItems"})
+	}
+
+	if value, ok := rules["oneof"]; ok {
+		var enum []interface{}
+		for _, item := range strings.Fields(value) {
+			if t.Kind() == reflect.String {
+				enum = append(enum, item)
+			} else if n, err := strconv.ParseFloat(item, 64); err == nil {
+				enum = append(enum, jsonNumber(n))
+			}
+		}
+		schema["enum"] = enum
+	}
+	for _, format := range []string{"email", "uri", "uuid", "ipv4", "ipv6"} {
+		if rules.has(format) {
+			schema["format"] = format
+		}
+	}
+	if rules.has("url") {
+		schema["format"] = "uri"
+	}
+}
+
+// jsonNumber 整数按整数输出，避免 JSON 和 YAML 中出现 1e+06 之类的表示
+func jsonNumber(n float64) interface{} {
+	if n == float64(int64(n)) {
+		return int64(n)
+	}
+	return n
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// writeYAML 将 map、slice 和基本类型组成的树输出为 YAML，字符串一律使用双引号，避免被解析为其他类型
+func writeYAML(b *strings.Builder, v interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.WriteString(pad + yamlKey(k) + ":")
+			writeYAMLValue(b, v[k], indent)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if m, ok := normalizeYAML(item).(map[string]interface{}); ok && len(m) > 0 {
+				// 对象作为列表项时，第一个键与 "-" 写在同一行
+				var sub strings.Builder
+				writeYAML(&sub, m, indent+1)
+				b.WriteString(pad + "- " + strings.TrimPrefix(sub.String(), pad+"  "))
+				continue
+			}
+			b.WriteString(pad + "-")
+			writeYAMLValue(b, item, indent)
+		}
+	}
+}
+
+// writeYAMLValue 输出键或列表项之后的值，非空的对象和列表换行并增加缩进，其余的值写在同一行
+func writeYAMLValue(b *strings.Builder, v interface{}, indent int) {
+	switch value := normalizeYAML(v).(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAML(b, value, indent+1)
+	case []interface{}:
+		if len(value) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAML(b, value, indent+1)
+	case string:
+		b.WriteString(" " + strconv.Quote(value) + "\n")
+	case nil:
+		b.WriteString(" null\n")
+	default:
+		data, _ := json.Marshal(value)
+		b.WriteString(" " + string(data) + "\n")
+	}
+}
+
+// normalizeYAML 将具体类型的 map 和 slice 转换为 writeYAML 能处理的类型
+func normalizeYAML(v interface{}) interface{} {
+	switch value := v.(type) {
+	case []string:
+		list := make([]interface{}, 0, len(value))
+		for _, s := range value {
+			list = append(list, s)
+		}
+		return list
+	case []map[string]interface{}:
+		list := make([]interface{}, 0, len(value))
+		for _, m := range value {
+			list = append(list, m)
+		}
+		return list
+	}
+	return v
+}
+
+// yamlKey 只包含字母、数字和少量符号且不以数字开头的键直接输出，否则加双引号
+func yamlKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for i, r := range key {
+		ok := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+			(i > 0 && (r == '-' || r == '.' || (r >= '0' && r <= '9')))
+		if !ok {
+			return strconv.Quote(key)
+		}
+	}
+	return key
+}
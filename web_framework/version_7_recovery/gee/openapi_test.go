@@ -0,0 +1,176 @@
+package gee
+
+import (
+	"bytes"
+	"flag"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"text/template"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+type pageQuery struct {
+	Page  int    `form:"page" binding:"min=1"`
+	Size  int    `form:"size" binding:"min=1,max=100"`
+	Token string `header:"X-Token" binding:"required"`
+}
+
+type address struct {
+	City string `json:"city" binding:"required"`
+}
+
+type createUserReq struct {
+	ID      int      `uri:"id"`
+	Name    string   `json:"name" binding:"required,min=2,max=32"`
+	Email   string   `json:"email,omitempty" binding:"email"`
+	Role    string   `json:"role" binding:"oneof=admin member"`
+	Tags    []string `json:"tags" binding:"max=5"`
+	Address *address `json:"address"`
+	secret  string
+}
+
+type user struct {
+	ID        int64             `json:"id"`
+	Name      string            `json:"name"`
+	CreatedAt time.Time         `json:"created_at"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Friends   []*user           `json:"friends"`
+	Ignored   string            `json:"-"`
+}
+
+type loginForm struct {
+	Username string `form:"username" binding:"required"`
+	Password string `form:"password" binding:"required,min=8"`
+	Remember bool   `form:"remember"`
+}
+
+func newOpenAPIEngine() *Engine {
+	r := New()
+	v1 := r.Group("/v1")
+	v1.GET("/users", func(c *Context) {},
+		Summary("List users"), Tags("user"), Request(pageQuery{}), Response(http.StatusOK, []user{}))
+	v1.PUT("/users/:id<int>", func(c *Context) {},
+		Name("user.update"), Summary("Update user"), Tags("user"),
+		Request(&createUserReq{}), Response(http.StatusOK, user{}), Response(http.StatusNotFound, H{}))
+	v1.GET("/files/*filepath", func(c *Context) {})
+	v1.POST("/login", func(c *Context) {}, Summary("Login with a form"), Request(loginForm{}))
+	return r
+}
+
+func TestOpenAPIGolden(t *testing.T) {
+	doc := GenerateOpenAPI(newOpenAPIEngine(), OpenAPIInfo{Title: "gee", Version: "1.0.0"})
+	jsonData, err := doc.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlData, err := doc.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, got := range map[string][]byte{"openapi.golden.json": jsonData, "openapi.golden.yaml": yamlData} {
+		golden := filepath.Join("testdata", name)
+		if *update {
+			if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("%v, run `go test -update` to create golden files", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s mismatch, run `go test -update` if the change is expected, got:\n%s", name, got)
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	r := newOpenAPIEngine()
+	handler := OpenAPIHandler(r, OpenAPIInfo{Title: "gee", Version: "1.0.0"})
+	r.GET("/openapi.json", handler)
+	r.GET("/openapi.yaml", handler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.yaml", nil))
+	if w.Header().Get("Content-Type") != "application/yaml" || !bytes.HasPrefix(w.Body.Bytes(), []byte("components:")) {
+		t.Fatalf("unexpected yaml response %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	if w.Header().Get("Content-Type") != "application/json" || !bytes.Contains(w.Body.Bytes(), []byte(`"/openapi.json"`)) {
+		t.Fatalf("unexpected json response %q", w.Body.String())
+	}
+}
+
+type userV2 struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+}
+
+func TestOpenAPIDuplicateOperations(t *testing.T) {
+	r := New()
+	r.Versioning(VersioningOptions{Header: "X-Version"})
+	api := r.Group("/api")
+	api.Version("1").GET("/users/:id", func(c *Context) {},
+		Summary("Get user"), Tags("user"), Response(http.StatusOK, user{}))
+	api.Version("2").GET("/users/:id", func(c *Context) {},
+		Tags("user", "v2"), Request(pageQuery{}), Response(http.StatusOK, userV2{}), Response(http.StatusNotFound, nil))
+	r.Host("admin.example.com").GET("/api/users/:id", func(c *Context) {}, Response(http.StatusOK, user{}))
+
+	paths := GenerateOpenAPI(r, OpenAPIInfo{Title: "gee", Version: "1.0.0"}).tree["paths"].(map[string]interface{})
+	op := paths["/api/users/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	if op["summary"] != "Get user" || !reflect.DeepEqual(op["tags"], []string{"user", "v2"}) {
+		t.Fatalf("unexpected merged operation %v", op)
+	}
+
+	params := map[string]interface{}{}
+	for _, p := range op["parameters"].([]interface{}) {
+		p := p.(map[string]interface{})
+		params[p["in"].(string)+":"+p["name"].(string)] = p["schema"]
+	}
+	if len(params) != 5 || params["path:id"] == nil || params["query:page"] == nil || params["header:X-Token"] == nil {
+		t.Fatalf("parameters should be merged, got %v", params)
+	}
+	if enum := params["header:X-Version"].(map[string]interface{})["enum"]; !reflect.DeepEqual(enum, []interface{}{"1", "2"}) {
+		t.Fatalf("unexpected version enum %v", enum)
+	}
+
+	responses := op["responses"].(map[string]interface{})
+	content := responses["200"].(map[string]interface{})["content"].(map[string]interface{})
+	schema := content["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+	want := []interface{}{
+		map[string]interface{}{"$ref": "#/components/schemas/gee.user"},
+		map[string]interface{}{"$ref": "#/components/schemas/gee.userV2"},
+	}
+	if !reflect.DeepEqual(schema["oneOf"], want) {
+		t.Fatalf("different response schemas should be listed in oneOf, got %v", schema)
+	}
+	if responses["404"] == nil {
+		t.Fatal("responses should be merged")
+	}
+}
+
+func TestOpenAPISchemaName(t *testing.T) {
+	g := &openAPIGenerator{
+		schemas: make(map[string]interface{}),
+		names:   make(map[reflect.Type]string),
+		types:   make(map[string]reflect.Type),
+	}
+	if name := g.schemaName(reflect.TypeOf(template.Template{})); name != "template.Template" {
+		t.Fatalf("unexpected name %s", name)
+	}
+	// 包名相同的另一个包使用完整的包路径
+	if name := g.schemaName(reflect.TypeOf(htmltemplate.Template{})); name != "html_template.Template" {
+		t.Fatalf("unexpected name %s", name)
+	}
+}
@@ -0,0 +1,455 @@
+package gee
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Backend 反向代理的一个后端
+type Backend struct {
+	URL *url.URL
+
+	active    int64 // 正在处理的请求数，用于最少连接负载均衡
+	fails     int32 // 连续失败次数，用于被动健康检查
+	downUntil int64 // 被动健康检查标记为不可用的截止时间（UnixNano）
+	unhealthy int32 // 主动健康检查的结果，1 表示不可用
+}
+
+// Alive 后端当前是否可用：主动健康检查通过，并且不在被动健康检查的隔离期内
+func (b *Backend) Alive() bool {
+	return atomic.LoadInt32(&b.unhealthy) == 0 && time.Now().UnixNano() >= atomic.LoadInt64(&b.downUntil)
+}
+
+// ActiveRequests 正在处理的请求数
+func (b *Backend) ActiveRequests() int64 {
+	return atomic.LoadInt64(&b.active)
+}
+
+// Balancer 负载均衡策略，从可用的后端中选出一个，backends 不为空
+type Balancer interface {
+	Pick(backends []*Backend, req *http.Request) *Backend
+}
+
+// RoundRobin 轮询
+func RoundRobin() Balancer {
+	return &roundRobin{}
+}
+
+type roundRobin struct {
+	next uint64
+}
+
+func (b *roundRobin) Pick(backends []*Backend, req *http.Request) *Backend {
+	n := atomic.AddUint64(&b.next, 1) - 1
+	return backends[n%uint64(len(backends))]
+}
+
+// LeastConnections 选择正在处理的请求数最少的后端，请求数相同时选择靠前的
+func LeastConnections() Balancer {
+	return leastConnections{}
+}
+
+type leastConnections struct{}
+
+func (leastConnections) Pick(backends []*Backend, req *http.Request) *Backend {
+	best := backends[0]
+	for _, b := range backends[1:] {
+		if b.ActiveRequests() < best.ActiveRequests() {
+			best = b
+		}
+	}
+	return best
+}
+
+// ConsistentHash 按请求头的值做一致性哈希，相同的值总是转发到同一个后端，例如按 X-User-ID 实现会话保持。
+// 请求头为空时退化为轮询。某个后端不可用时，只有原本属于它的请求会转移到哈希环上的下一个后端。
+func ConsistentHash(header string) Balancer {
+	return &consistentHash{header: header, replicas: 50, fallback: &roundRobin{}}
+}
+
+type consistentHash struct {
+	header   string
+	replicas int // 虚拟节点的倍数
+	fallback Balancer
+
+	mu      sync.Mutex
+	members string           // 构建哈希环时的后端列表，列表变化时重建哈希环
+	keys    []int            // 哈希环
+	hashMap map[int]*Backend // 虚拟节点和真实节点的映射表
+}
+
+func (b *consistentHash) Pick(backends []*Backend, req *http.Request) *Backend {
+	value := req.Header.Get(b.header)
+	if value == "" {
+		return b.fallback.Pick(backends, req)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.build(backends)
+
+	hash := int(crc32.ChecksumIEEE([]byte(value)))
+	index := sort.Search(len(b.keys), func(i int) bool { return b.keys[i] >= hash }) // 二分查找第一个不小于 hash 的虚拟节点
+	return b.hashMap[b.keys[index%len(b.keys)]]
+}
+
+// build 可用的后端发生变化时重建哈希环，虚拟节点只与后端地址有关，因此其余后端上的请求不受影响
+func (b *consistentHash) build(backends []*Backend) {
+	names := make([]string, len(backends))
+	for i, backend := range backends {
+		names[i] = backend.URL.String()
+	}
+	members := strings.Join(names, ",")
+	if members == b.members {
+		return
+	}
+
+	b.members = members
+	b.keys = b.keys[:0]
+	b.hashMap = make(map[int]*Backend)
+	for _, backend := range backends {
+		for i := 0; i < b.replicas; i++ {
+			hash := int(crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + backend.URL.String())))
+			b.keys = append(b.keys, hash)
+			b.hashMap[hash] = backend
+		}
+	}
+	sort.Ints(b.keys)
+}
+
+// ProxyRewrite 路径重写规则，Match 匹配时将路径替换为 Replace，Replace 中可以使用 $1 引用分组
+type ProxyRewrite struct {
+	Match   *regexp.Regexp
+	Replace string
+}
+
+// ProxyHealthCheck 主动健康检查的配置
+type ProxyHealthCheck struct {
+	Path     string        // 检查的路径，例如 /healthz，返回 2xx 或 3xx 视为健康
+	Interval time.Duration // 检查间隔，默认 10s
+	Timeout  time.Duration // 单次检查的超时时间，默认 2s
+}
+
+// ProxyOptions 反向代理的配置
+type ProxyOptions struct {
+	Balancer Balancer // 负载均衡策略，默认轮询
+
+	StripPrefix string         // 转发前从路径中去掉的前缀，例如 "/api"
+	Rewrites    []ProxyRewrite // 在 StripPrefix 之后依次执行的路径重写规则
+
+	SetRequestHeaders     map[string]string // 转发前设置的请求头
+	RemoveRequestHeaders  []string          // 转发前删除的请求头，例如 Cookie
+	SetResponseHeaders    map[string]string // 返回给客户端前设置的响应头
+	RemoveResponseHeaders []string          // 返回给客户端前删除的响应头，例如 Server
+
+	Retries int // 幂等请求（GET、HEAD、OPTIONS、PUT、DELETE）遇到连接错误时，换一个后端重试的次数
+
+	MaxFails    int           // 被动健康检查：连续失败多少次后暂时摘除后端，默认 3
+	FailTimeout time.Duration // 被动健康检查：摘除后端的时长，默认 10s
+
+	HealthCheck *ProxyHealthCheck // 主动健康检查，为 nil 时不开启
+
+	Transport http.RoundTripper // 转发请求使用的 Transport，默认 http.DefaultTransport
+}
+
+// ReverseProxy 带负载均衡和健康检查的反向代理
+type ReverseProxy struct {
+	backends []*Backend
+	opts     ProxyOptions
+	proxy    *httputil.ReverseProxy
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// Proxy 创建反向代理并返回处理函数，例如 r.Any("/api/*path", gee.Proxy(targets, gee.ProxyOptions{StripPrefix: "/api"}))。
+// targets 不合法时 panic，需要停止主动健康检查时使用 NewReverseProxy。
+func Proxy(targets []string, opts ProxyOptions) HandlerFunc {
+	p, err := NewReverseProxy(targets, opts)
+	if err != nil {
+		panic(err)
+	}
+	return p.Handler()
+}
+
+// NewReverseProxy 创建反向代理，配置了 HealthCheck 时启动主动健康检查，调用 Close 停止
+func NewReverseProxy(targets []string, opts ProxyOptions) (*ReverseProxy, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("gee: proxy needs at least one target")
+	}
+	if opts.Balancer == nil {
+		opts.Balancer = RoundRobin()
+	}
+	if opts.MaxFails <= 0 {
+		opts.MaxFails = 3
+	}
+	if opts.FailTimeout <= 0 {
+		opts.FailTimeout = 10 * time.Second
+	}
+
+	p := &ReverseProxy{opts: opts, stop: make(chan struct{})}
+	for _, target := range targets {
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("gee: invalid proxy target %q", target)
+		}
+		p.backends = append(p.backends, &Backend{URL: u})
+	}
+
+	p.proxy = &httputil.ReverseProxy{
+		Director:       p.director,
+		Transport:      opts.Transport,
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler:   p.errorHandler,
+	}
+
+	if opts.HealthCheck != nil {
+		go p.healthCheckLoop()
+	}
+	return p, nil
+}
+
+// Backends 返回所有后端
+func (p *ReverseProxy) Backends() []*Backend {
+	return p.backends
+}
+
+// Close 停止主动健康检查
+func (p *ReverseProxy) Close() {
+	p.once.Do(func() { close(p.stop) })
+}
+
+// proxyAttempt 一次转发尝试，通过请求的 context 传递给 Director 和 ErrorHandler
+type proxyAttempt struct {
+	backend *Backend
+	err     error
+}
+
+type proxyAttemptKey struct{}
+
+// idempotentMethods 可以安全重试的请求方法
+var idempotentMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodOptions: true,
+	http.MethodPut: true, http.MethodDelete: true, http.MethodTrace: true,
+}
+
+// Handler 返回转发请求的处理函数
+func (p *ReverseProxy) Handler() HandlerFunc {
+	return func(c *Context) {
+		req := c.Req
+		retries := 0
+		var body []byte
+		if idempotentMethods[req.Method] && p.opts.Retries > 0 {
+			retries = p.opts.Retries
+			if req.Body != nil && req.Body != http.NoBody { // 重试时需要重新发送请求体，先读入内存
+				data, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					c.Fail(http.StatusBadRequest, err.Error())
+					return
+				}
+				body = data
+			}
+		}
+
+		tried := make(map[*Backend]bool)
+		for i := 0; i <= retries; i++ {
+			backend := p.pick(req, tried)
+			if backend == nil && i > 0 { // 所有后端都已尝试过
+				break
+			}
+			if backend == nil {
+				c.Fail(http.StatusServiceUnavailable, "no available backend")
+				return
+			}
+			tried[backend] = true
+
+			attempt := &proxyAttempt{backend: backend}
+			out := req.WithContext(context.WithValue(req.Context(), proxyAttemptKey{}, attempt))
+			if body != nil {
+				out.Body = ioutil.NopCloser(bytes.NewReader(body))
+				out.ContentLength = int64(len(body))
+			}
+
+			p.forward(c.Writer, out, backend)
+
+			if attempt.err == nil {
+				p.markSuccess(backend)
+				return
+			}
+			c.Error(attempt.err)
+			if errors.Is(attempt.err, context.Canceled) || req.Context().Err() != nil {
+				return // 客户端断开了连接，不是后端的问题，也无需重试
+			}
+			p.markFailure(backend)
+		}
+		c.Fail(http.StatusBadGateway, "bad gateway")
+	}
+}
+
+// forward 转发到 backend。复制响应体中途失败时 httputil.ReverseProxy 会以 http.ErrAbortHandler panic，
+// 因此在 defer 中减少连接数
+func (p *ReverseProxy) forward(w http.ResponseWriter, req *http.Request, backend *Backend) {
+	atomic.AddInt64(&backend.active, 1)
+	defer atomic.AddInt64(&backend.active, -1)
+	p.proxy.ServeHTTP(w, req)
+}
+
+// pick 从可用且本次请求未尝试过的后端中选择一个
+func (p *ReverseProxy) pick(req *http.Request, tried map[*Backend]bool) *Backend {
+	var candidates []*Backend
+	for _, b := range p.backends {
+		if b.Alive() && !tried[b] {
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return p.opts.Balancer.Pick(candidates, req)
+}
+
+func (p *ReverseProxy) markSuccess(b *Backend) {
+	atomic.StoreInt32(&b.fails, 0)
+}
+
+// markFailure 连续失败达到 MaxFails 次后，在 FailTimeout 内不再向该后端转发请求
+func (p *ReverseProxy) markFailure(b *Backend) {
+	if int(atomic.AddInt32(&b.fails, 1)) >= p.opts.MaxFails {
+		atomic.StoreInt64(&b.downUntil, time.Now().Add(p.opts.FailTimeout).UnixNano())
+		atomic.StoreInt32(&b.fails, 0)
+	}
+}
+
+// director 修改转发的请求：目标地址、重写后的路径、请求头
+func (p *ReverseProxy) director(req *http.Request) {
+	attempt := req.Context().Value(proxyAttemptKey{}).(*proxyAttempt)
+	target := attempt.backend.URL
+
+	path := strings.TrimPrefix(req.URL.Path, p.opts.StripPrefix)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	for _, rule := range p.opts.Rewrites {
+		if rule.Match.MatchString(path) {
+			path = rule.Match.ReplaceAllString(path, rule.Replace)
+		}
+	}
+
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	req.URL.Path = singleJoiningSlash(target.Path, path)
+	req.URL.RawPath = ""
+	if target.RawQuery != "" && req.URL.RawQuery != "" {
+		req.URL.RawQuery = target.RawQuery + "&" + req.URL.RawQuery
+	} else if target.RawQuery != "" {
+		req.URL.RawQuery = target.RawQuery
+	}
+
+	if req.Header.Get("X-Forwarded-Host") == "" {
+		req.Header.Set("X-Forwarded-Host", req.Host)
+	}
+	if req.Header.Get("X-Forwarded-Proto") == "" {
+		if req.TLS != nil {
+			req.Header.Set("X-Forwarded-Proto", "https")
+		} else {
+			req.Header.Set("X-Forwarded-Proto", "http")
+		}
+	}
+	for _, key := range p.opts.RemoveRequestHeaders {
+		req.Header.Del(key)
+	}
+	for key, value := range p.opts.SetRequestHeaders {
+		req.Header.Set(key, value)
+	}
+}
+
+func (p *ReverseProxy) modifyResponse(resp *http.Response) error {
+	for _, key := range p.opts.RemoveResponseHeaders {
+		resp.Header.Del(key)
+	}
+	for key, value := range p.opts.SetResponseHeaders {
+		resp.Header.Set(key, value)
+	}
+	return nil
+}
+
+// errorHandler 连接后端失败时还没有向客户端写入任何内容，只记录错误，由 Handler 决定重试或返回 502
+func (p *ReverseProxy) errorHandler(w http.ResponseWriter, req *http.Request, err error) {
+	attempt := req.Context().Value(proxyAttemptKey{}).(*proxyAttempt)
+	attempt.err = err
+}
+
+func (p *ReverseProxy) healthCheckLoop() {
+	interval := p.opts.HealthCheck.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	p.checkBackends()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.checkBackends()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// checkBackends 并发检查所有后端，更新主动健康检查的结果
+func (p *ReverseProxy) checkBackends() {
+	timeout := p.opts.HealthCheck.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	client := &http.Client{Timeout: timeout, Transport: p.opts.Transport}
+
+	var wg sync.WaitGroup
+	for _, b := range p.backends {
+		wg.Add(1)
+		go func(b *Backend) {
+			defer wg.Done()
+			healthy := false
+			u := *b.URL
+			u.Path = singleJoiningSlash(u.Path, p.opts.HealthCheck.Path)
+			if resp, err := client.Get(u.String()); err == nil {
+				_, _ = io.Copy(ioutil.Discard, resp.Body)
+				resp.Body.Close()
+				healthy = resp.StatusCode < 400
+			}
+			if healthy {
+				atomic.StoreInt32(&b.unhealthy, 0)
+			} else {
+				atomic.StoreInt32(&b.unhealthy, 1)
+			}
+		}(b)
+	}
+	wg.Wait()
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
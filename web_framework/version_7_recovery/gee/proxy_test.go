@@ -0,0 +1,273 @@
+package gee
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newBackend 启动一个返回自身名称和请求路径的后端
+func newBackend(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Server", "backend")
+		w.Header().Set("X-Backend", name)
+		w.Header().Set("X-Got-Token", req.Header.Get("X-Token"))
+		w.Header().Set("X-Got-Cookie", req.Header.Get("Cookie"))
+		body, _ := ioutil.ReadAll(req.Body)
+		fmt.Fprintf(w, "%s %s %s", name, req.URL.RequestURI(), body)
+	}))
+}
+
+func proxyGet(r *Engine, path string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestProxyRoundRobin(t *testing.T) {
+	a, b := newBackend("a"), newBackend("b")
+	defer a.Close()
+	defer b.Close()
+
+	r := New()
+	r.Any("/api/*path", Proxy([]string{a.URL, b.URL}, ProxyOptions{StripPrefix: "/api"}))
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		w := proxyGet(r, "/api/users?page=2", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d", w.Code)
+		}
+		got = append(got, w.Body.String())
+	}
+	want := []string{"a /users?page=2 ", "b /users?page=2 ", "a /users?page=2 ", "b /users?page=2 "}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestProxyRewriteAndHeaders(t *testing.T) {
+	a := newBackend("a")
+	defer a.Close()
+
+	r := New()
+	r.GET("/v1/*path", Proxy([]string{a.URL + "/base"}, ProxyOptions{
+		StripPrefix:           "/v1",
+		Rewrites:              []ProxyRewrite{{Match: regexp.MustCompile(`^/user/(\d+)$`), Replace: "/users/$1/profile"}},
+		SetRequestHeaders:     map[string]string{"X-Token": "secret"},
+		RemoveRequestHeaders:  []string{"Cookie"},
+		SetResponseHeaders:    map[string]string{"X-Gateway": "gee"},
+		RemoveResponseHeaders: []string{"Server"},
+	}))
+
+	w := proxyGet(r, "/v1/user/42", map[string]string{"Cookie": "session=1"})
+	if w.Body.String() != "a /base/users/42/profile " {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	if w.Header().Get("X-Got-Token") != "secret" || w.Header().Get("X-Got-Cookie") != "" {
+		t.Fatalf("request headers not modified: %v", w.Header())
+	}
+	if w.Header().Get("X-Gateway") != "gee" || w.Header().Get("Server") != "" {
+		t.Fatalf("response headers not modified: %v", w.Header())
+	}
+}
+
+func TestProxyLeastConnections(t *testing.T) {
+	a, b := newBackend("a"), newBackend("b")
+	defer a.Close()
+	defer b.Close()
+
+	p, err := NewReverseProxy([]string{a.URL, b.URL}, ProxyOptions{Balancer: LeastConnections()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	atomic.AddInt64(&p.Backends()[0].active, 5) // 模拟 a 上有 5 个处理中的请求
+	r := New()
+	r.GET("/", p.Handler())
+
+	for i := 0; i < 3; i++ {
+		if w := proxyGet(r, "/", nil); !strings.HasPrefix(w.Body.String(), "b ") {
+			t.Fatalf("expected backend b, got %q", w.Body.String())
+		}
+	}
+}
+
+func TestProxyConsistentHash(t *testing.T) {
+	var urls []string
+	for _, name := range []string{"a", "b", "c"} {
+		s := newBackend(name)
+		defer s.Close()
+		urls = append(urls, s.URL)
+	}
+
+	r := New()
+	r.GET("/", Proxy(urls, ProxyOptions{Balancer: ConsistentHash("X-User-ID")}))
+
+	backends := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		first := proxyGet(r, "/", map[string]string{"X-User-ID": user}).Header().Get("X-Backend")
+		for j := 0; j < 3; j++ {
+			if got := proxyGet(r, "/", map[string]string{"X-User-ID": user}).Header().Get("X-Backend"); got != first {
+				t.Fatalf("%s: expected sticky backend %s, got %s", user, first, got)
+			}
+		}
+		backends[first] = true
+	}
+	if len(backends) < 2 {
+		t.Fatalf("keys should spread over backends, got %v", backends)
+	}
+}
+
+func TestProxyRetryAndPassiveHealthCheck(t *testing.T) {
+	a := newBackend("a")
+	defer a.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close() // 已关闭的后端，连接会被拒绝
+
+	p, err := NewReverseProxy([]string{dead.URL, a.URL}, ProxyOptions{Retries: 1, MaxFails: 2, FailTimeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.Any("/", p.Handler())
+
+	// 幂等请求失败后换一个后端重试，请求体也会重新发送
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest("PUT", "/", strings.NewReader("data"))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != "a / data" {
+			t.Fatalf("request %d: got %d %q", i, w.Code, w.Body.String())
+		}
+	}
+	if p.Backends()[0].Alive() {
+		t.Fatal("dead backend should be marked down after MaxFails failures")
+	}
+
+	// 非幂等请求不重试
+	p2, _ := NewReverseProxy([]string{dead.URL}, ProxyOptions{Retries: 3})
+	r2 := New()
+	r2.POST("/", p2.Handler())
+	w := httptest.NewRecorder()
+	r2.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("data")))
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d", w.Code)
+	}
+}
+
+func TestProxyActiveHealthCheck(t *testing.T) {
+	var healthy int32 = 1
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/healthz" && atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer a.Close()
+
+	p, err := NewReverseProxy([]string{a.URL}, ProxyOptions{
+		HealthCheck: &ProxyHealthCheck{Path: "/healthz", Interval: 10 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	r := New()
+	r.GET("/", p.Handler())
+
+	waitFor := func(alive bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for p.Backends()[0].Alive() != alive {
+			if time.Now().After(deadline) {
+				t.Fatalf("backend alive should become %v", alive)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	atomic.StoreInt32(&healthy, 0)
+	waitFor(false)
+	if w := proxyGet(r, "/", nil); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without healthy backends, got %d", w.Code)
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	waitFor(true)
+	if w := proxyGet(r, "/", nil); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 after recovery, got %d", w.Code)
+	}
+}
+
+func TestNewReverseProxyInvalidTarget(t *testing.T) {
+	if _, err := NewReverseProxy(nil, ProxyOptions{}); err == nil {
+		t.Fatal("expected error for empty targets")
+	}
+	if _, err := NewReverseProxy([]string{"localhost:8080"}, ProxyOptions{}); err == nil {
+		t.Fatal("expected error for target without scheme")
+	}
+}
+
+func TestProxyClientCancel(t *testing.T) {
+	started := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-req.Context().Done()
+	}))
+	defer slow.Close()
+	p, _ := NewReverseProxy([]string{slow.URL}, ProxyOptions{Retries: 1, MaxFails: 1, FailTimeout: time.Minute})
+	r := New()
+	r.GET("/", p.Handler())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	// 客户端断开连接不算后端失败
+	if b := p.Backends()[0]; !b.Alive() || b.ActiveRequests() != 0 {
+		t.Fatalf("backend should stay alive with no active requests, alive=%v active=%d", b.Alive(), b.ActiveRequests())
+	}
+}
+
+func TestProxyAbortReleasesActive(t *testing.T) {
+	// 后端声明的长度与实际发送的不符，复制响应体失败时 ReverseProxy 以 http.ErrAbortHandler panic
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, buf, _ := w.(http.Hijacker).Hijack()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\nshort")
+		buf.Flush()
+		conn.Close()
+	}))
+	defer broken.Close()
+	p, _ := NewReverseProxy([]string{broken.URL}, ProxyOptions{})
+	r := New()
+	r.GET("/", p.Handler())
+
+	func() {
+		defer func() {
+			if err := recover(); err != http.ErrAbortHandler {
+				t.Fatalf("expected http.ErrAbortHandler, got %v", err)
+			}
+		}()
+		req := httptest.NewRequest("GET", "/", nil)
+		// 没有 http.ServerContextKey 时 ReverseProxy 认为不在 http.Server 中，不会 panic
+		req = req.WithContext(context.WithValue(req.Context(), http.ServerContextKey, &http.Server{}))
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	if active := p.Backends()[0].ActiveRequests(); active != 0 {
+		t.Fatalf("expected 0 active requests after abort, got %d", active)
+	}
+}
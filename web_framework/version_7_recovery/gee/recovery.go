@@ -1,36 +1,229 @@
 package gee
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"html/template"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"os"
 	"runtime"
 	"strings"
+	"syscall"
 )
 
+// DefaultErrorWriter Recovery 默认输出日志的位置
+var DefaultErrorWriter io.Writer = os.Stderr
+
+// RecoveryFunc 处理 panic 的函数，err 为 recover() 的返回值
+type RecoveryFunc func(c *Context, err interface{})
+
+// Recovery 捕获 panic，将错误和调用栈写入 DefaultErrorWriter，返回 500。开发模式下返回带有调用栈和源码的 HTML 页面
 func Recovery() HandlerFunc {
+	return RecoveryWithWriter(DefaultErrorWriter)
+}
+
+// CustomRecovery 使用自定义的函数处理 panic，例如返回统一格式的错误响应
+func CustomRecovery(handle RecoveryFunc) HandlerFunc {
+	return RecoveryWithWriter(DefaultErrorWriter, handle)
+}
+
+// RecoveryWithWriter 将日志写入 out，out 为 nil 时不输出日志；handle 为空时使用默认的处理函数
+func RecoveryWithWriter(out io.Writer, handle ...RecoveryFunc) HandlerFunc {
+	var logger *log.Logger
+	if out != nil {
+		logger = log.New(out, "", log.LstdFlags)
+	}
+	handler := defaultRecoveryHandler
+	if len(handle) > 0 && handle[0] != nil {
+		handler = handle[0]
+	}
+
 	return func(c *Context) {
 		defer func() {
-			if err := recover(); err != nil { // 捕获 panic error
-				log.Printf("%s\n\n", trace(err))                                // 解析 error
-				c.Fail(http.StatusInternalServerError, "Internal Server Error") // 给用户返回 500 错误
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler { // 处理函数主动中断连接，交给 net/http 处理，不输出日志
+				panic(err)
+			}
+
+			runErrorHooks(c, err)
+			if isBrokenPipe(err) {
+				// 客户端已经断开连接，无法再写入响应，也不需要输出调用栈
+				if logger != nil {
+					logger.Printf("gee: client disconnected: %s %s: %v", c.Method, c.Path, err)
+				}
+				c.Error(err.(error))
+				c.Abort()
+				return
 			}
+
+			if logger != nil {
+				logger.Printf("%s\n\n", trace(err)) // 解析 error
+			}
+			c.Error(fmt.Errorf("panic: %v", err))
+			handler(c, err)
+			c.Abort()
 		}()
 		c.Next() // 为了保证 defer 的正确执行顺序，务必需要写 c.Next
 	}
 }
 
+// defaultRecoveryHandler 返回 500，已经写入了部分响应时不再写入
+func defaultRecoveryHandler(c *Context, err interface{}) {
+	if c.Written() {
+		return
+	}
+	if IsDebugging() {
+		c.SetHeader("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusInternalServerError)
+		_ = panicPageTemplate.Execute(c.Writer, newPanicPage(c, err))
+		return
+	}
+	c.Fail(http.StatusInternalServerError, "Internal Server Error") // 给用户返回 500 错误
+}
+
+// isBrokenPipe 判断是否是客户端断开连接导致的写入错误，例如 broken pipe、connection reset by peer
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(e, &opErr) {
+		return false
+	}
+	if errors.Is(opErr, syscall.EPIPE) || errors.Is(opErr, syscall.ECONNRESET) {
+		return true
+	}
+	msg := strings.ToLower(opErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
+
 func trace(error interface{}) string {
 	message := fmt.Sprintf("%s", error)
-	var pcs [32]uintptr
-	n := runtime.Callers(3, pcs[:]) // skip first 3 caller
 
 	var str strings.Builder
 	str.WriteString(message + "\nTraceback:")
-	for _, pc := range pcs[:n] {
-		fn := runtime.FuncForPC(pc)
-		file, line := fn.FileLine(pc)
-		str.WriteString(fmt.Sprintf("\n\t%s:%d", file, line))
+	for _, frame := range panicStack() {
+		str.WriteString(fmt.Sprintf("\n\t%s:%d", frame.File, frame.Line))
 	}
 	return str.String()
 }
+
+// stackFrame 调用栈中的一帧
+type stackFrame struct {
+	Func string
+	File string
+	Line int
+}
+
+// panicStack 在 defer 中调用，返回从 panic 发生的位置开始的调用栈。
+// 通过查找 runtime.gopanic 确定起点，不依赖固定的调用层数；找不到时返回完整的调用栈
+func panicStack() []stackFrame {
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:]) // 跳过 runtime.Callers 和 panicStack 本身
+
+	var all []stackFrame
+	start := 0
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		all = append(all, stackFrame{Func: frame.Function, File: frame.File, Line: frame.Line})
+		if frame.Function == "runtime.gopanic" {
+			start = len(all)
+		}
+		if !more {
+			break
+		}
+	}
+	return all[start:]
+}
+
+// panicPage 开发模式下 panic 页面的数据
+type panicPage struct {
+	Error  string
+	Method string
+	URL    string
+	Frames []panicFrame
+}
+
+type panicFrame struct {
+	stackFrame
+	Source []sourceLine
+}
+
+type sourceLine struct {
+	Number  int
+	Text    string
+	Current bool
+}
+
+// panicSourceFrames 展示源码的帧数，panicSourceContext 为出错行前后展示的行数
+const (
+	panicSourceFrames  = 10
+	panicSourceContext = 5
+)
+
+func newPanicPage(c *Context, err interface{}) panicPage {
+	page := panicPage{Error: fmt.Sprintf("%v", err), Method: c.Method, URL: c.Req.URL.String()}
+	for i, frame := range panicStack() {
+		f := panicFrame{stackFrame: frame}
+		if i < panicSourceFrames {
+			f.Source = readSource(frame.File, frame.Line, panicSourceContext)
+		}
+		page.Frames = append(page.Frames, f)
+	}
+	return page
+}
+
+// readSource 读取 file 中第 line 行前后各 context 行，文件不存在时返回 nil
+func readSource(file string, line int, context int) []sourceLine {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []sourceLine
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan() && n <= line+context; n++ {
+		if n >= line-context {
+			lines = append(lines, sourceLine{Number: n, Text: scanner.Text(), Current: n == line})
+		}
+	}
+	return lines
+}
+
+var panicPageTemplate = template.Must(template.New("panic").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>panic: {{.Error}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+h1 { color: #c00; }
+.frame { margin-bottom: 1.5em; }
+.func { font-weight: bold; }
+.file { color: #666; }
+pre { background: #f6f6f6; padding: .5em; overflow-x: auto; }
+.current { background: #fdd; display: block; }
+</style>
+</head>
+<body>
+<h1>panic: {{.Error}}</h1>
+<p>{{.Method}} {{.URL}}</p>
+{{range .Frames}}<div class="frame">
+<div class="func">{{.Func}}</div>
+<div class="file">{{.File}}:{{.Line}}</div>
+{{if .Source}}<pre>{{range .Source}}<span{{if .Current}} class="current"{{end}}>{{printf "%4d" .Number}}  {{.Text}}</span>
+{{end}}</pre>{{end}}
+</div>
+{{end}}</body>
+</html>
+`))
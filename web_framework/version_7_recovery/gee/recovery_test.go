@@ -0,0 +1,118 @@
+package gee
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(RecoveryWithWriter(&buf))
+	r.GET("/panic", func(c *Context) {
+		panic("boom") // recovery_test.go:19
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "Internal Server Error") {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	// 调用栈从 panic 发生的位置开始
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 3 || !strings.Contains(lines[0], "boom") || !strings.HasSuffix(lines[2], "recovery_test.go:19") {
+		t.Fatalf("unexpected log %q", buf.String())
+	}
+}
+
+func TestCustomRecovery(t *testing.T) {
+	r := New()
+	r.Use(RecoveryWithWriter(nil, func(c *Context, err interface{}) {
+		c.JSON(http.StatusServiceUnavailable, H{"error": err})
+	}))
+	r.GET("/panic", func(c *Context) {
+		panic("maintenance")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"maintenance"`) {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRecoveryBrokenPipe(t *testing.T) {
+	for _, errno := range []syscall.Errno{syscall.EPIPE, syscall.ECONNRESET} {
+		var buf bytes.Buffer
+		var errs []error
+		r := New()
+		r.Use(func(c *Context) {
+			c.Next()
+			errs = c.Errors
+		})
+		r.Use(RecoveryWithWriter(&buf))
+		r.GET("/", func(c *Context) {
+			panic(&net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", errno)})
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+			t.Fatalf("%v: should not write to a disconnected client, got %q", errno, w.Body.String())
+		}
+		if strings.Contains(buf.String(), "Traceback") || !strings.Contains(buf.String(), "client disconnected") {
+			t.Fatalf("%v: unexpected log %q", errno, buf.String())
+		}
+		if len(errs) != 1 {
+			t.Fatalf("%v: error should be recorded, got %v", errno, errs)
+		}
+	}
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	r := New()
+	r.Use(RecoveryWithWriter(nil))
+	r.GET("/", func(c *Context) {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Fatalf("expected http.ErrAbortHandler to be re-panicked, got %v", err)
+		}
+	}()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestRecoveryDebugPage(t *testing.T) {
+	SetMode(DebugMode)
+	defer SetMode(ReleaseMode)
+
+	r := New()
+	r.Use(RecoveryWithWriter(nil))
+	r.GET("/", func(c *Context) {
+		panic("<script>") // 出错的源码行会出现在页面中
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/?q=1", nil))
+	body := w.Body.String()
+	if w.Code != http.StatusInternalServerError || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if strings.Contains(body, "<script>") || !strings.Contains(body, "panic: &lt;script&gt;") {
+		t.Fatal("panic message should be escaped")
+	}
+	if !strings.Contains(body, `class="current"`) || !strings.Contains(body, "出错的源码行会出现在页面中") {
+		t.Fatalf("page should include source snippets: %s", body)
+	}
+	if !strings.Contains(body, "GET /?q=1") {
+		t.Fatal("page should include the request")
+	}
+}
@@ -0,0 +1,55 @@
+package gee
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// responseWriter 包装 http.ResponseWriter，记录响应的状态码和大小，供中间件（例如 Metrics）使用
+type responseWriter struct {
+	http.ResponseWriter
+	status int // 已写入的状态码，为 0 表示还未写入
+	size   int // 已写入的响应体字节数
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+// Flush 实现 http.Flusher，底层不支持时什么也不做
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack 实现 http.Hijacker，用于 WebSocket 等需要接管连接的场景
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("gee: response writer does not implement http.Hijacker")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap 返回底层的 http.ResponseWriter，供 http.ResponseController 使用
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,137 @@
+package gee
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// RewriteRule URL 重写或重定向规则，Host、Headers、Match 都满足时规则生效
+type RewriteRule struct {
+	Host    string                    // 只匹配该 Host（忽略端口和大小写），*.example.com 匹配所有子域名，为空时匹配任意 Host
+	Headers map[string]*regexp.Regexp // 请求头必须匹配的正则表达式，请求头不存在时按空字符串匹配
+	Match   *regexp.Regexp            // 匹配路径，为空时匹配任意路径
+	// Replace 新的地址，可以使用 $1、${name} 引用 Match 中的分组，可以带查询参数；
+	// Match 没有匹配整个路径时，只替换匹配的部分，因此通常以 ^ 和 $ 包围
+	Replace  string
+	Redirect int // 0 表示内部重写，301、302、307、308 表示重定向，重定向时 Replace 也可以是完整的 URL
+}
+
+// Rewrite 返回按顺序执行重写规则的钩子，只执行第一条生效的规则，通过 engine.OnRequest 注册，在路由之前执行。
+//   - 内部重写修改请求路径，路由、分组中间件都按新的路径匹配，Replace 带查询参数时替换原有的查询参数；
+//   - 重定向直接返回响应，Replace 不带查询参数时保留原有的查询参数。
+func Rewrite(rules ...RewriteRule) Hook {
+	for i, rule := range rules {
+		switch rule.Redirect {
+		case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			panic(fmt.Sprintf("gee: rewrite rule %d: invalid redirect status %d", i, rule.Redirect))
+		}
+	}
+
+	return func(c *Context) {
+		for _, rule := range rules {
+			target, ok := rule.apply(c)
+			if !ok {
+				continue
+			}
+			if rule.Redirect != 0 {
+				if !strings.Contains(target, "?") && c.Req.URL.RawQuery != "" {
+					target += "?" + c.Req.URL.RawQuery
+				}
+				http.Redirect(c.Writer, c.Req, target, rule.Redirect)
+				c.Abort()
+				return
+			}
+
+			path := target
+			if i := strings.IndexByte(target, '?'); i >= 0 {
+				path = target[:i]
+				c.Req.URL.RawQuery = target[i+1:]
+				c.queryCache = nil
+			}
+			c.Path = path
+			c.Req.URL.Path = path
+			c.Req.URL.RawPath = ""
+			return
+		}
+	}
+}
+
+// apply 判断规则是否生效，生效时返回替换后的地址
+func (rule *RewriteRule) apply(c *Context) (string, bool) {
+	if rule.Host != "" && !matchRewriteHost(rule.Host, c.Req.Host) {
+		return "", false
+	}
+	for key, re := range rule.Headers {
+		if !re.MatchString(c.Req.Header.Get(key)) {
+			return "", false
+		}
+	}
+	if rule.Match == nil {
+		return rule.Replace, true
+	}
+	if !rule.Match.MatchString(c.Path) {
+		return "", false
+	}
+	return rule.Match.ReplaceAllString(c.Path, rule.Replace), true
+}
+
+func matchRewriteHost(pattern, host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	pattern = strings.ToLower(pattern)
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
+
+// overridableMethods 允许通过 MethodOverride 改写的请求方法
+var overridableMethods = map[string]bool{
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// DefaultMaxMethodOverrideSize MethodOverride 默认允许解析的表单请求体大小
+const DefaultMaxMethodOverrideSize = 10 << 20
+
+// MethodOverride 返回改写请求方法的钩子，通过 engine.OnRequest 注册，使 HTML 表单也能访问 PUT、PATCH、DELETE 路由。
+// 只改写 POST 请求，依次读取 X-HTTP-Method-Override 请求头和请求体表单中的 _method 字段（不读取查询参数），
+// 其他的值被忽略。表单最多解析 DefaultMaxMethodOverrideSize 字节，见 MethodOverrideWithLimit
+func MethodOverride() Hook {
+	return MethodOverrideWithLimit(DefaultMaxMethodOverrideSize)
+}
+
+// MethodOverrideWithLimit 与 MethodOverride 相同，表单请求体最多解析 maxSize 字节。
+// 钩子在所有中间件之前执行并解析整个表单，BodyLimit 对这里的读取不生效，因此需要单独限制；
+// 超过限制时不改写请求方法，解析错误记录在 c.Errors 中
+func MethodOverrideWithLimit(maxSize int64) Hook {
+	return func(c *Context) {
+		if c.Method != http.MethodPost {
+			return
+		}
+		method := c.Req.Header.Get("X-HTTP-Method-Override")
+		if method == "" && isFormRequest(c.Req) { // 只解析表单请求，不读取 JSON 等其他请求体
+			if c.Req.Body != nil && c.Req.Body != http.NoBody {
+				c.Req.Body = http.MaxBytesReader(c.writer.ResponseWriter, c.Req.Body, maxSize)
+			}
+			method, _ = c.GetPostForm("_method")
+		}
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if overridableMethods[method] {
+			c.Method = method
+		}
+	}
+}
+
+func isFormRequest(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && (mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data")
+}
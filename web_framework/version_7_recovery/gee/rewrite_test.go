@@ -0,0 +1,149 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	r := New()
+	r.OnRequest(Rewrite(
+		RewriteRule{Match: regexp.MustCompile(`^/old/(\w+)$`), Replace: "/new/$1", Redirect: http.StatusMovedPermanently},
+		RewriteRule{Host: "*.example.com", Match: regexp.MustCompile(`^/docs/(.*)$`), Replace: "https://docs.example.com/$1", Redirect: http.StatusFound},
+		RewriteRule{Headers: map[string]*regexp.Regexp{"Accept": regexp.MustCompile(`application/vnd\.v2`)},
+			Match: regexp.MustCompile(`^/users/(?P<id>\d+)$`), Replace: "/v2/users/${id}"},
+		RewriteRule{Match: regexp.MustCompile(`^/u/(\d+)$`), Replace: "/users/$1?from=short"},
+	))
+	v2 := r.Group("/v2")
+	v2.Use(func(c *Context) { c.SetHeader("X-Group", "v2") })
+	v2.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "v2 user %s", c.Param("id"))
+	})
+	r.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "user %s %s %s", c.Param("id"), c.Query("from"), c.Req.RequestURI)
+	})
+
+	cases := []struct {
+		host, path, accept string
+		code               int
+		location, body     string
+	}{
+		{"", "/old/abc?x=1", "", http.StatusMovedPermanently, "/new/abc?x=1", ""},
+		{"api.example.com", "/docs/a/b", "", http.StatusFound, "https://docs.example.com/a/b", ""},
+		{"example.org", "/docs/a/b", "", http.StatusNotFound, "", ""},
+		{"", "/users/1", "application/vnd.v2+json", http.StatusOK, "", "v2 user 1"},
+		{"", "/users/1", "application/json", http.StatusOK, "", "user 1  /users/1"},
+		{"", "/u/2?from=x", "", http.StatusOK, "", "user 2 short /u/2?from=x"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		if tc.host != "" {
+			req.Host = tc.host
+		}
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code || w.Header().Get("Location") != tc.location {
+			t.Fatalf("%s %s: expected %d %q, got %d %q", tc.host, tc.path, tc.code, tc.location, w.Code, w.Header().Get("Location"))
+		}
+		if tc.body != "" && w.Body.String() != tc.body {
+			t.Fatalf("%s: expected body %q, got %q", tc.path, tc.body, w.Body.String())
+		}
+		if strings.HasPrefix(tc.body, "v2") && w.Header().Get("X-Group") != "v2" {
+			t.Fatalf("%s: group middleware should match the rewritten path", tc.path)
+		}
+	}
+}
+
+func TestRewriteInvalidRedirect(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for invalid redirect status")
+		}
+	}()
+	Rewrite(RewriteRule{Replace: "/", Redirect: http.StatusOK})
+}
+
+func TestMethodOverride(t *testing.T) {
+	r := New()
+	r.OnRequest(MethodOverride())
+	r.PUT("/items/:id", func(c *Context) {
+		c.String(http.StatusOK, "put %s %s", c.Param("id"), c.PostForm("name"))
+	})
+	r.DELETE("/items/:id", func(c *Context) {
+		c.String(http.StatusOK, "delete %s", c.Param("id"))
+	})
+	r.POST("/items/:id", func(c *Context) {
+		c.String(http.StatusOK, "post %s", c.Param("id"))
+	})
+
+	cases := []struct {
+		contentType, header, body, want string
+	}{
+		{"application/x-www-form-urlencoded", "", "_method=put&name=apple", "put 1 apple"},
+		{"", "DELETE", "", "delete 1"},
+		{"application/json", "", `{"_method":"DELETE"}`, "post 1"}, // 不读取非表单的请求体
+		{"application/x-www-form-urlencoded", "", "_method=CONNECT", "post 1"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("POST", "/items/1", strings.NewReader(tc.body))
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		if tc.header != "" {
+			req.Header.Set("X-HTTP-Method-Override", tc.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != tc.want {
+			t.Fatalf("%q %q: expected %q, got %d %q", tc.header, tc.body, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	// 只改写 POST 请求
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("X-HTTP-Method-Override", "DELETE")
+	r.ServeHTTP(w, req)
+	if w.Code == http.StatusOK {
+		t.Fatalf("GET should not be overridden, got %q", w.Body.String())
+	}
+
+	// 只读取请求体中的 _method，查询参数被忽略
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/items/1?_method=DELETE", strings.NewReader("name=apple"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "post 1" {
+		t.Fatalf("_method in query should be ignored, got %q", w.Body.String())
+	}
+}
+
+func TestMethodOverrideLimit(t *testing.T) {
+	r := New()
+	r.OnRequest(MethodOverrideWithLimit(32))
+	r.DELETE("/items/:id", func(c *Context) {
+		c.String(http.StatusOK, "delete %s", c.Param("id"))
+	})
+	r.POST("/items/:id", func(c *Context) {
+		c.String(http.StatusOK, "post %s", c.Param("id"))
+	})
+
+	for body, want := range map[string]string{
+		"_method=delete": "delete 1",
+		"_method=delete&name=" + strings.Repeat("a", 64): "post 1", // 超过限制时不解析表单
+	} {
+		req := httptest.NewRequest("POST", "/items/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != want {
+			t.Fatalf("%q: expected %q, got %q", body, want, w.Body.String())
+		}
+	}
+}
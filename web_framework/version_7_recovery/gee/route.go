@@ -0,0 +1,127 @@
+package gee
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// RouteInfo 描述一条已注册的路由，由 Engine.Routes 返回
+type RouteInfo struct {
+	Host        string // 路由所属的 Host 模式，默认分组下的路由为空
+	Method      string // 请求方法，例如 GET
+	Pattern     string // 完整的路由地址，包含分组前缀，例如 /v1/user/:id
+	Version     string // 路由所属的 API 版本，通过 group.Version 注册，未分版本时为空
+	Handler     string // 处理函数的名称，例如 main.main.func1
+	Middlewares int    // 对该路由生效的中间件数量
+	Name        string // 路由名称，通过 Name 选项设置，用于反向生成 URL
+
+	// 以下为接口文档信息，用于生成 OpenAPI 文档
+	Summary     string              // 接口摘要，通过 Summary 选项设置
+	Description string              // 接口描述，通过 Description 选项设置
+	Tags        []string            // 接口分类，通过 Tags 选项设置
+	Request     interface{}         // 请求参数结构体，通过 Request 选项设置
+	Responses   map[int]interface{} // 各状态码对应的响应结构体，通过 Response 选项设置
+}
+
+// RouteOption 注册路由时的可选配置，例如 r.GET("/user/:id", handler, gee.Name("user.show"))
+type RouteOption func(info *RouteInfo)
+
+// Name 为路由命名，之后可以通过 engine.URLFor 或模板函数 url 反向生成 URL
+func Name(name string) RouteOption {
+	return func(info *RouteInfo) {
+		info.Name = name
+	}
+}
+
+// Summary 设置接口摘要
+func Summary(summary string) RouteOption {
+	return func(info *RouteInfo) {
+		info.Summary = summary
+	}
+}
+
+// Description 设置接口描述
+func Description(description string) RouteOption {
+	return func(info *RouteInfo) {
+		info.Description = description
+	}
+}
+
+// Tags 设置接口分类
+func Tags(tags ...string) RouteOption {
+	return func(info *RouteInfo) {
+		info.Tags = append(info.Tags, tags...)
+	}
+}
+
+// Request 设置请求参数结构体，v 可以是结构体的值或指针，例如 gee.Request(CreateUserReq{})。
+// 结构体字段通过 uri、form、header 标签声明路径、查询和请求头参数，通过 json 标签声明请求体。
+func Request(v interface{}) RouteOption {
+	return func(info *RouteInfo) {
+		info.Request = v
+	}
+}
+
+// Response 设置某个状态码的响应结构体，例如 gee.Response(200, User{})，v 为 nil 表示没有响应体
+func Response(code int, v interface{}) RouteOption {
+	return func(info *RouteInfo) {
+		if info.Responses == nil {
+			info.Responses = make(map[int]interface{})
+		}
+		info.Responses[code] = v
+	}
+}
+
+// Routes 返回所有已注册的路由，先返回默认分组的路由，再按 Host 分组的创建顺序返回，同一棵路由树内与注册顺序一致
+func (engine *Engine) Routes() []RouteInfo {
+	var routes []RouteInfo
+	for _, r := range engine.routers() {
+		for _, info := range r.routes {
+			route := *info
+			route.Middlewares = engine.countMiddlewares(r, info.Pattern, info.Version)
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// countMiddlewares 统计对某个路由地址生效的中间件数量，匹配规则与 ServeHTTP 中一致
+func (engine *Engine) countMiddlewares(r *router, pattern string, version string) int {
+	count := len(engine.groupMiddlewares(r, pattern, ""))
+	if version != "" {
+		count += len(engine.groupMiddlewares(r, pattern, version))
+	}
+	return count
+}
+
+// URLFor 根据路由名称反向生成 URL，params 用于填充 :param 和 *wildcard 片段，参数值会被转义
+func (engine *Engine) URLFor(name string, params map[string]string) (string, error) {
+	for _, r := range engine.routers() {
+		if _, ok := r.named[name]; ok {
+			return r.urlFor(name, params)
+		}
+	}
+	return "", fmt.Errorf("gee: route %q not found", name)
+}
+
+// urlFunc 模板中使用的 url 函数，参数以键值对的形式传入，例如 {{ url "user.show" "id" .ID }}
+func (engine *Engine) urlFunc(name string, pairs ...interface{}) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("gee: url %q expects key/value pairs, got %d arguments", name, len(pairs))
+	}
+	params := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return "", fmt.Errorf("gee: url %q param key must be string, got %T", name, pairs[i])
+		}
+		params[key] = fmt.Sprint(pairs[i+1])
+	}
+	return engine.URLFor(name, params)
+}
+
+// nameOfFunction 获取函数的名称，例如 main.main.func1
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
@@ -0,0 +1,72 @@
+package gee
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) {})
+	v1 := r.Group("/v1")
+	v1.Use(func(c *Context) {})
+	r.GET("/", func(c *Context) {})
+	v1.POST("/user/:id", func(c *Context) {}, Name("user.update"))
+
+	routes := r.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expect 2 routes, got %d", len(routes))
+	}
+	if routes[0].Method != "GET" || routes[0].Pattern != "/" || routes[0].Middlewares != 1 {
+		t.Fatalf("unexpected route %+v", routes[0])
+	}
+	if routes[1].Pattern != "/v1/user/:id" || routes[1].Name != "user.update" || routes[1].Middlewares != 2 {
+		t.Fatalf("unexpected route %+v", routes[1])
+	}
+	if routes[1].Handler == "" {
+		t.Fatal("expect handler name")
+	}
+}
+
+func TestURLFor(t *testing.T) {
+	r := New()
+	r.GET("/user/:id", func(c *Context) {}, Name("user.show"))
+	r.GET("/assets/*filepath", func(c *Context) {}, Name("assets"))
+
+	cases := []struct {
+		name   string
+		params map[string]string
+		want   string
+	}{
+		{"user.show", map[string]string{"id": "a/b c"}, "/user/a%2Fb%20c"},
+		{"assets", map[string]string{"filepath": "css/my file.css"}, "/assets/css/my%20file.css"},
+	}
+	for _, c := range cases {
+		got, err := r.URLFor(c.name, c.params)
+		if err != nil || got != c.want {
+			t.Fatalf("URLFor(%q) = %q, %v; want %q", c.name, got, err, c.want)
+		}
+	}
+
+	if _, err := r.URLFor("user.show", nil); err == nil {
+		t.Fatal("expect error for missing param")
+	}
+	if _, err := r.URLFor("unknown", nil); err == nil {
+		t.Fatal("expect error for unknown route")
+	}
+}
+
+func TestURLFuncInTemplate(t *testing.T) {
+	r := New()
+	r.GET("/user/:id", func(c *Context) {}, Name("user.show"))
+
+	tmpl := template.Must(template.New("").Funcs(r.builtinFuncMap()).Parse(`<a href="{{ url "user.show" "id" 7 }}">`))
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != `<a href="/user/7">` {
+		t.Fatalf("unexpected output %s", buf.String())
+	}
+}
@@ -1,20 +1,33 @@
 package gee
 
-import "fmt"
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
 
 type router struct {
+	host     string // 路由树对应的 Host 模式，默认路由树为空
 	roots    map[string]*node
 	handlers map[string]HandlerFunc
+	// 版本分组注册的处理函数，method-pattern -> 版本 -> 处理函数，同一路由地址未分版本的处理函数仍保存在 handlers 中
+	versioned map[string]map[string]HandlerFunc
+
+	routes []*RouteInfo          // 按注册顺序保存的路由信息，用于路由自省
+	named  map[string]*RouteInfo // 具名路由，用于反向生成 URL
 }
 
 func newRouter() *router {
 	return &router{
-		roots:    make(map[string]*node),
-		handlers: make(map[string]HandlerFunc),
+		roots:     make(map[string]*node),
+		handlers:  make(map[string]HandlerFunc),
+		versioned: make(map[string]map[string]HandlerFunc),
+		named:     make(map[string]*RouteInfo),
 	}
 }
 
-func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
+// addRoute 注册路由，version 不为空时注册为该版本的处理函数
+func (r *router) addRoute(method string, pattern string, version string, handler HandlerFunc, opts ...RouteOption) {
 	// 添加请求方法，例如 GET、POST
 	if _, ok := r.roots[method]; !ok {
 		r.roots[method] = &node{children: make(map[string]*node)}
@@ -23,11 +36,59 @@ func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
 	r.roots[method].insert(pattern)
 
 	key := method + "-" + pattern
-	r.handlers[key] = handler
+	if version == "" {
+		r.handlers[key] = handler
+	} else {
+		if r.versioned[key] == nil {
+			r.versioned[key] = make(map[string]HandlerFunc)
+		}
+		r.versioned[key][version] = handler
+	}
+
+	r.addRouteInfo(method, pattern, version, handler, opts)
 
 	fmt.Println("key", key)
 }
 
+// addRouteInfo 记录路由信息，同一个 method + pattern + version 重复注册时，覆盖之前的记录
+func (r *router) addRouteInfo(method string, pattern string, version string, handler HandlerFunc, opts []RouteOption) {
+	info := &RouteInfo{
+		Host:    r.host,
+		Method:  method,
+		Pattern: pattern,
+		Version: version,
+		Handler: nameOfFunction(handler),
+	}
+	for _, opt := range opts {
+		opt(info)
+	}
+
+	replaced := false
+	for i, old := range r.routes {
+		if old.Method == method && old.Pattern == pattern && old.Version == version {
+			if old.Name != "" && r.named[old.Name] == old {
+				delete(r.named, old.Name)
+			}
+			r.routes[i] = info
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		r.routes = append(r.routes, info)
+	}
+
+	if info.Name != "" {
+		exist, ok := r.named[info.Name]
+		if ok && exist.Pattern != info.Pattern { // 同一路由地址的不同请求方法（例如 Any）可以共用名称
+			panic(fmt.Sprintf("gee: route name %q is already used by %s %s", info.Name, exist.Method, exist.Pattern))
+		}
+		if !ok {
+			r.named[info.Name] = info
+		}
+	}
+}
+
 func (r *router) getRouter(method string, pattern string) (*node, map[string]string) {
 	if _, ok := r.roots[method]; !ok {
 		return nil, nil
@@ -39,11 +100,74 @@ func (r *router) getRouter(method string, pattern string) (*node, map[string]str
 func (r *router) handle(c *Context) {
 	node, params := r.getRouter(c.Method, c.Path)
 	if node != nil {
-		c.Params = params
+		if c.Params == nil {
+			c.Params = params
+		} else { // 已经保存了 Host 中的参数，合并路由参数
+			for k, v := range params {
+				c.Params[k] = v
+			}
+		}
 		key := c.Method + "-" + node.path
-		c.handlers = append(c.handlers, r.handlers[key])
-	} else {
-		fmt.Fprintf(c.Writer, "404 NOT FOUND: %s\n", c.Req.URL)
+		c.fullPath = node.path
+		handler := r.handlers[key]
+		if versions, ok := r.versioned[key]; ok { // 分版本的路由，按请求协商版本，并加入版本分组的中间件
+			c.version, handler = c.engine.negotiateVersion(c, versions, handler)
+			if c.version != "" {
+				c.handlers = append(c.handlers, c.engine.groupMiddlewares(r, c.Path, c.version)...)
+			}
+		}
+		c.handlers = append(c.handlers, handler)
+		c.engine.runRouteMatchedHooks(c)
+	} else { // 未匹配时，依次执行中间件和 NoRoute/NoMethod 处理函数
+		c.handlers = append(c.handlers, c.engine.fallbackHandlers(r, c)...)
 	}
 	c.Next()
 }
+
+// urlFor 根据路由名称和参数，反向生成 URL。
+// 例如路由 "/user/:id/*filepath"，参数 {"id": "1", "filepath": "a b/c"}，得到 "/user/1/a%20b/c"
+func (r *router) urlFor(name string, params map[string]string) (string, error) {
+	info, ok := r.named[name]
+	if !ok {
+		return "", fmt.Errorf("gee: route %q not found", name)
+	}
+
+	parts := parsePath(info.Pattern)
+	segments := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part == "" || (part[0] != ':' && part[0] != '*') { // 普通片段，原样保留
+			segments = append(segments, part)
+			continue
+		}
+
+		key, constraint, err := parseParam(part)
+		if err != nil {
+			return "", err
+		}
+		value, ok := params[key]
+		if !ok {
+			return "", fmt.Errorf("gee: missing param %q for route %q", key, name)
+		}
+		if constraint != "" { // 生成的 URL 需要能匹配回该路由
+			re, err := compileConstraint(constraint)
+			if err != nil {
+				return "", err
+			}
+			if !re.MatchString(value) {
+				return "", fmt.Errorf("gee: param %q=%q does not satisfy constraint <%s> of route %q", key, value, constraint, name)
+			}
+		}
+		if part[0] == ':' { // 命名参数只占一个片段，斜杠也需要转义
+			segments = append(segments, url.PathEscape(value))
+			continue
+		}
+
+		// 通配符参数可以跨越多个片段，逐段转义，保留斜杠
+		wild := strings.Split(strings.TrimPrefix(value, "/"), "/")
+		for i := range wild {
+			wild[i] = url.PathEscape(wild[i])
+		}
+		segments = append(segments, strings.Join(wild, "/"))
+	}
+	return "/" + strings.Join(segments, "/"), nil
+}
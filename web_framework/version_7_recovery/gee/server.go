@@ -0,0 +1,70 @@
+package gee
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// GracefulShutdownTimeout RunGraceful 关闭时等待处理中的请求完成的最长时间
+var GracefulShutdownTimeout = 30 * time.Second
+
+// GracefulRestartTimeout RunGraceful 重启时等待新进程就绪的最长时间，超时后旧进程继续提供服务
+var GracefulRestartTimeout = 10 * time.Second
+
+// ShutdownDrainDelay Shutdown 执行完回调后，继续接受新请求的时间，默认为 0。
+// 负载均衡需要一段时间才能发现就绪检查失败，期间仍可能转发新请求，立即停止监听会导致这些请求失败
+var ShutdownDrainDelay time.Duration
+
+func (engine *Engine) setServer(server *http.Server) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.server = server
+}
+
+// RegisterOnShutdown 注册 Shutdown 开始时执行的回调，例如让就绪检查失败，使负载均衡不再转发新请求
+func (engine *Engine) RegisterOnShutdown(f func()) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.onShutdown = append(engine.onShutdown, f)
+}
+
+// IsShuttingDown 是否已经开始 Shutdown
+func (engine *Engine) IsShuttingDown() bool {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	return engine.shuttingDown
+}
+
+// Shutdown 优雅关闭 Run 启动的服务：先执行 RegisterOnShutdown 注册的回调，等待 ShutdownDrainDelay，
+// 然后停止接受新连接，等待处理中的请求完成，ctx 超时后返回 ctx 的错误
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	server := engine.beginShutdown(ctx, true)
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// beginShutdown 标记开始关闭并执行回调，drain 为 true 且服务正在运行时，再继续接受新请求 ShutdownDrainDelay，
+// 返回 Run 创建的 http.Server
+func (engine *Engine) beginShutdown(ctx context.Context, drain bool) *http.Server {
+	engine.mu.Lock()
+	engine.shuttingDown = true
+	hooks := append([]func(){}, engine.onShutdown...)
+	server := engine.server
+	engine.mu.Unlock()
+
+	for _, f := range hooks {
+		f()
+	}
+	if server != nil && drain && ShutdownDrainDelay > 0 {
+		timer := time.NewTimer(ShutdownDrainDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+	return server
+}
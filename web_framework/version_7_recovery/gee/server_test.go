@@ -0,0 +1,63 @@
+package gee
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrainDelay(t *testing.T) {
+	defer func(d time.Duration) { ShutdownDrainDelay = d }(ShutdownDrainDelay)
+	ShutdownDrainDelay = 100 * time.Millisecond
+
+	r := New()
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+	r.setServer(ts.Config)
+
+	hooked := make(chan struct{})
+	r.RegisterOnShutdown(func() { close(hooked) })
+	done := make(chan error)
+	start := time.Now()
+	go func() { done <- r.Shutdown(context.Background()) }()
+
+	// 回调执行之后、停止监听之前，仍然可以处理新请求
+	<-hooked
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("request during drain delay failed: %v", err)
+	}
+	resp.Body.Close()
+	if !r.IsShuttingDown() {
+		t.Fatal("engine should be shutting down")
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < ShutdownDrainDelay {
+		t.Fatalf("Shutdown returned after %v, before the drain delay", elapsed)
+	}
+}
+
+func TestShutdownDrainDelayContext(t *testing.T) {
+	defer func(d time.Duration) { ShutdownDrainDelay = d }(ShutdownDrainDelay)
+	ShutdownDrainDelay = time.Minute
+
+	r := New()
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+	r.setServer(ts.Config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	r.Shutdown(ctx)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("drain delay should stop when ctx is done, took %v", elapsed)
+	}
+}
@@ -0,0 +1,36 @@
+// graceful 是 graceful_linux_test.go 使用的测试程序：/pid 返回进程号，/slow 等待 500ms 后返回进程号。
+// 设置了环境变量 GRACEFUL_FAIL_RESTART 时，重启出的新进程没有就绪就直接退出；
+// GRACEFUL_DRAIN_DELAY 设置 gee.ShutdownDrainDelay，/readyz 为就绪检查
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"geeweb/gee"
+	"geeweb/gee/health"
+)
+
+func main() {
+	if os.Getenv("GRACEFUL_FAIL_RESTART") != "" && os.Getenv("GEE_LISTENER_FD") != "" {
+		log.Fatal("restart failed")
+	}
+	if delay, err := time.ParseDuration(os.Getenv("GRACEFUL_DRAIN_DELAY")); err == nil {
+		gee.ShutdownDrainDelay = delay
+	}
+	r := gee.New()
+	health.New().Register(r)
+	r.GET("/pid", func(c *gee.Context) {
+		c.String(http.StatusOK, strconv.Itoa(os.Getpid()))
+	})
+	r.GET("/slow", func(c *gee.Context) {
+		time.Sleep(500 * time.Millisecond)
+		c.String(http.StatusOK, strconv.Itoa(os.Getpid()))
+	})
+	if err := r.RunGraceful(os.Args[1]); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,16 @@
+package gee
+
+import "net/http"
+
+// CreateTestContext 创建一个不经过路由的 Context，用于单独测试中间件或处理函数。
+// handlers 为依次执行的处理函数，调用 c.Next() 开始执行，例如：
+//
+//	c, _ := gee.CreateTestContext(w, req, Recovery(), func(c *Context) { panic("err") })
+//	c.Next()
+func CreateTestContext(w http.ResponseWriter, req *http.Request, handlers ...HandlerFunc) (*Context, *Engine) {
+	engine := New()
+	c := NewContext(w, req)
+	c.engine = engine
+	c.handlers = handlers
+	return c, engine
+}
@@ -0,0 +1,269 @@
+package gee
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceID W3C Trace Context 中的 trace-id，16 字节
+type TraceID [16]byte
+
+// SpanID W3C Trace Context 中的 parent-id，8 字节
+type SpanID [8]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+// IsValid 全 0 的 id 是无效的
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+func (id SpanID) IsValid() bool  { return id != SpanID{} }
+
+func (id TraceID) MarshalJSON() ([]byte, error) { return json.Marshal(id.String()) }
+func (id SpanID) MarshalJSON() ([]byte, error)  { return json.Marshal(id.String()) }
+
+// SpanContext 跨服务传播的链路信息，对应 traceparent 请求头
+type SpanContext struct {
+	TraceID    TraceID `json:"trace_id"`
+	SpanID     SpanID  `json:"span_id"`
+	TraceFlags byte    `json:"trace_flags"`
+	TraceState string  `json:"trace_state,omitempty"` // tracestate 请求头，原样传递
+}
+
+// IsSampled 是否被采样，对应 trace-flags 的最低位
+func (sc SpanContext) IsSampled() bool {
+	return sc.TraceFlags&0x01 == 0x01
+}
+
+// Traceparent 编码为 traceparent 请求头的值，例如 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.TraceFlags)
+}
+
+// ParseTraceparent 解析 traceparent 请求头，格式为 version-traceid-parentid-flags
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, fmt.Errorf("gee: invalid traceparent %q", value)
+	}
+	if parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) { // 00 版本只能有 4 段，更高的版本允许在后面追加字段
+		return sc, fmt.Errorf("gee: invalid traceparent version in %q", value)
+	}
+	if !isLowerHex(parts[0]) || !isLowerHex(parts[1]) || !isLowerHex(parts[2]) || !isLowerHex(parts[3]) {
+		return sc, fmt.Errorf("gee: invalid traceparent %q", value)
+	}
+
+	_, _ = hex.Decode(sc.TraceID[:], []byte(parts[1]))
+	_, _ = hex.Decode(sc.SpanID[:], []byte(parts[2]))
+	flags, _ := hex.DecodeString(parts[3])
+	sc.TraceFlags = flags[0]
+	if !sc.TraceID.IsValid() || !sc.SpanID.IsValid() {
+		return SpanContext{}, fmt.Errorf("gee: all-zero id in traceparent %q", value)
+	}
+	return sc, nil
+}
+
+func isLowerHex(s string) bool {
+	for _, r := range s {
+		if !(r >= '0' && r <= '9') && !(r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Span 一次请求处理过程，请求结束时交给 SpanExporter 导出
+type Span struct {
+	Name         string                 `json:"name"`
+	SpanContext  SpanContext            `json:"context"`
+	ParentSpanID SpanID                 `json:"parent_span_id"`
+	StartTime    time.Time              `json:"start_time"`
+	EndTime      time.Time              `json:"end_time"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Status       string                 `json:"status"` // "ok" 或 "error"
+	Errors       []string               `json:"errors,omitempty"`
+
+	mu sync.Mutex
+}
+
+// SetAttribute 设置属性，可以在处理函数中通过 SpanFromContext(c.Req.Context()) 获取 Span 后调用
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]interface{})
+	}
+	s.Attributes[key] = value
+}
+
+// RecordError 记录错误，并将 Span 的状态设置为 error
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Errors = append(s.Errors, err.Error())
+	s.Status = "error"
+}
+
+// SpanExporter 导出结束的 Span，可以实现该接口对接 Jaeger、Zipkin 等系统
+type SpanExporter interface {
+	ExportSpan(span *Span)
+}
+
+// JSONExporter 将 Span 以 JSON 格式逐行写入 w，例如 NewJSONExporter(os.Stdout)
+type JSONExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w}
+}
+
+func (e *JSONExporter) ExportSpan(span *Span) {
+	span.mu.Lock()
+	data, err := json.Marshal(span)
+	span.mu.Unlock()
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, _ = e.w.Write(append(data, '\n'))
+}
+
+// InMemoryExporter 将 Span 保存在内存中，用于测试
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) ExportSpan(span *Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans 返回已导出的 Span
+func (e *InMemoryExporter) Spans() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Span(nil), e.spans...)
+}
+
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan 将 Span 保存到 context.Context 中
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext 从 context.Context 中获取 Span，不存在时返回 nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// InjectTraceparent 将 ctx 中的链路信息写入请求头，用于调用下游服务时继续传播
+func InjectTraceparent(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	header.Set("traceparent", span.SpanContext.Traceparent())
+	if span.SpanContext.TraceState != "" {
+		header.Set("tracestate", span.SpanContext.TraceState)
+	}
+}
+
+// Tracing 链路追踪中间件。请求携带合法的 traceparent 时延续上游的链路，否则开启新的链路；
+// 每个请求创建一个以路由地址命名的 Span，例如 "GET /user/:id"，并通过 c.Req.Context() 传递给处理函数，
+// 请求结束时记录状态码和错误（c.Error 记录的错误、5xx 状态码、panic），然后交给 exporter 导出。
+func Tracing(exporter SpanExporter) HandlerFunc {
+	return func(c *Context) {
+		parent, err := ParseTraceparent(c.Req.Header.Get("traceparent"))
+		hasParent := err == nil
+
+		sc := SpanContext{TraceFlags: 0x01}
+		if hasParent {
+			sc.TraceID, sc.TraceFlags = parent.TraceID, parent.TraceFlags
+			sc.TraceState = c.Req.Header.Get("tracestate")
+		} else {
+			_, _ = rand.Read(sc.TraceID[:])
+		}
+		_, _ = rand.Read(sc.SpanID[:])
+
+		route := c.FullPath()
+		name := c.Method
+		if route != "" {
+			name += " " + route
+		}
+		span := &Span{
+			Name:        name,
+			SpanContext: sc,
+			StartTime:   time.Now(),
+			Status:      "ok",
+		}
+		if hasParent {
+			span.ParentSpanID = parent.SpanID
+		}
+		span.SetAttribute("http.method", c.Method)
+		span.SetAttribute("http.target", c.Req.URL.RequestURI())
+		if route != "" {
+			span.SetAttribute("http.route", route)
+		}
+		c.Req = c.Req.WithContext(ContextWithSpan(c.Req.Context(), span))
+
+		defer func() {
+			if err := recover(); err != nil { // 记录 panic 后继续向上抛出，交给 Recovery 处理
+				span.RecordError(fmt.Errorf("panic: %v", err))
+				finishSpan(c, span, exporter)
+				panic(err)
+			}
+			finishSpan(c, span, exporter)
+		}()
+		c.Next()
+	}
+}
+
+func finishSpan(c *Context, span *Span, exporter SpanExporter) {
+	status := c.ResponseStatus()
+	if status == 0 {
+		status = c.StatusCode
+	}
+	if status != 0 {
+		span.SetAttribute("http.status_code", status)
+	}
+	for _, err := range c.Errors {
+		span.RecordError(err)
+	}
+	if status >= http.StatusInternalServerError {
+		span.mu.Lock()
+		span.Status = "error"
+		span.mu.Unlock()
+	}
+	span.EndTime = time.Now()
+	if span.SpanContext.IsSampled() {
+		exporter.ExportSpan(span)
+	}
+}
@@ -0,0 +1,114 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.IsSampled() {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+	if sc.Traceparent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatalf("unexpected traceparent %s", sc.Traceparent())
+	}
+
+	for _, value := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, err := ParseTraceparent(value); err == nil {
+			t.Fatalf("expect error for %q", value)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	r := New()
+	r.Use(Tracing(exporter))
+	r.GET("/user/:id", func(c *Context) {
+		span := SpanFromContext(c.Req.Context())
+		span.SetAttribute("user.id", c.Param("id"))
+
+		header := make(http.Header)
+		InjectTraceparent(c.Req.Context(), header)
+		c.String(http.StatusOK, header.Get("traceparent"))
+	})
+	r.GET("/fail", func(c *Context) {
+		c.Error(errors.New("db unavailable"))
+		c.String(http.StatusServiceUnavailable, "unavailable")
+	})
+
+	req := httptest.NewRequest("GET", "/user/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expect 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /user/:id" || span.Status != "ok" {
+		t.Fatalf("unexpected span %+v", span)
+	}
+	if span.SpanContext.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.ParentSpanID.String() != "00f067aa0ba902b7" {
+		t.Fatalf("span should continue the incoming trace, got %+v", span.SpanContext)
+	}
+	if span.Attributes["http.status_code"] != 200 || span.Attributes["user.id"] != "7" || span.Attributes["http.route"] != "/user/:id" {
+		t.Fatalf("unexpected attributes %v", span.Attributes)
+	}
+	if w.Body.String() != span.SpanContext.Traceparent() {
+		t.Fatalf("downstream traceparent should carry the server span, got %q", w.Body.String())
+	}
+
+	exporter.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	span = exporter.Spans()[0]
+	if span.Status != "error" || len(span.Errors) != 1 || span.ParentSpanID.IsValid() || !span.SpanContext.TraceID.IsValid() {
+		t.Fatalf("unexpected span %+v", span)
+	}
+}
+
+func TestTracingUnsampled(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	r := New()
+	r.Use(Tracing(exporter))
+	r.GET("/", func(c *Context) {})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if len(exporter.Spans()) != 0 {
+		t.Fatal("unsampled span should not be exported")
+	}
+}
+
+func TestJSONExporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(Tracing(NewJSONExporter(&buf)))
+	r.GET("/", func(c *Context) {})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var span map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &span); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if span["name"] != "GET /" {
+		t.Fatalf("unexpected span %v", span)
+	}
+}
@@ -0,0 +1,67 @@
+package gee
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// Translator 翻译器，由 i18n 中间件根据请求选择的语言设置到 Context 上，实现见 geeweb/gee/i18n
+type Translator interface {
+	// Locale 翻译器对应的语言，例如 zh-CN，相同语言的翻译器应当返回相同的翻译结果
+	Locale() string
+	// T 翻译 key 对应的文本，args 用于格式化和选择复数形式
+	T(key string, args ...interface{}) string
+}
+
+// SetTranslator 设置当前请求使用的翻译器
+func (c *Context) SetTranslator(t Translator) {
+	c.translator = t
+}
+
+// Translator 返回当前请求使用的翻译器，未设置时返回 nil
+func (c *Context) Translator() Translator {
+	return c.translator
+}
+
+// T 使用当前请求的翻译器翻译 key，未设置翻译器时原样返回 key（key 中含有 % 时使用 args 格式化）
+func (c *Context) T(key string, args ...interface{}) string {
+	if c.translator == nil {
+		return untranslated(key, args)
+	}
+	return c.translator.T(key, args...)
+}
+
+// untranslated 未翻译的文本。args 使用切片而不是可变参数，避免 T 被 go vet 当作 Printf 一类的函数检查
+func untranslated(key string, args []interface{}) string {
+	if len(args) == 0 || !strings.Contains(key, "%") {
+		return key
+	}
+	return fmt.Sprintf(key, args...)
+}
+
+// htmlTemplate 返回渲染当前请求使用的模板。设置了翻译器时，模板中的 t 函数需要使用该翻译器，
+// 模板函数在解析时绑定，因此为每种语言从未执行过的模板克隆一份，替换 t 函数后缓存起来
+func (c *Context) htmlTemplate() *template.Template {
+	engine := c.engine
+	if c.translator == nil || engine.htmlBase == nil {
+		return engine.htmlTemplates
+	}
+	if _, ok := engine.funcMap["t"]; ok { // 自定义了 t 函数时不再替换
+		return engine.htmlTemplates
+	}
+
+	locale := c.translator.Locale()
+	engine.templateMu.Lock()
+	defer engine.templateMu.Unlock()
+	if tmpl, ok := engine.localizedTemplates[locale]; ok {
+		return tmpl
+	}
+	tmpl := template.Must(engine.htmlBase.Clone())
+	tmpl.Funcs(template.FuncMap{"t": c.translator.T})
+	if engine.localizedTemplates == nil {
+		engine.localizedTemplates = make(map[string]*template.Template)
+	}
+	engine.localizedTemplates[locale] = tmpl
+	return tmpl
+}
@@ -1,12 +1,30 @@
 package gee
 
-import "strings"
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
 
 type node struct {
 	path     string           // 匹配上的完整的路由地址，只有最后节点才能保存 path
 	part     string           // 当前节点的 URL 片段
 	children map[string]*node // 储存后续片段的节点
 	isWild   bool             // 是否是通配符节点
+
+	param      string         // 通配符节点的参数名，例如 ":id<int>" 的参数名为 "id"
+	constraint *regexp.Regexp // 参数约束，例如 ":id<int>"，为 nil 表示不约束
+	wilds      []*node        // 通配符子节点，按匹配优先级排序：带约束的 : 节点、不带约束的 : 节点、* 节点
+}
+
+// paramConstraints 内置的参数约束，例如 /user/:id<int>，其余的约束按正则表达式处理，例如 /file/:name<[a-z]+\.txt>
+var paramConstraints = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"alpha": `[A-Za-z]+`,
+	"alnum": `[A-Za-z0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
 }
 
 func parsePath(pattern string) []string {
@@ -26,6 +44,75 @@ func parsePath(pattern string) []string {
 	return patterns
 }
 
+// parseParam 解析通配符片段，得到参数名和约束，例如 ":id<int>" 得到 "id" 和 "int"
+func parseParam(part string) (name string, constraint string, err error) {
+	name = part[1:]
+	if i := strings.IndexByte(name, '<'); i >= 0 {
+		if name[len(name)-1] != '>' {
+			return "", "", fmt.Errorf("gee: unclosed constraint in %q", part)
+		}
+		name, constraint = name[:i], name[i+1:len(name)-1]
+		if constraint == "" {
+			return "", "", fmt.Errorf("gee: empty constraint in %q", part)
+		}
+		if part[0] == '*' {
+			return "", "", fmt.Errorf("gee: constraint is not supported on catch-all param %q", part)
+		}
+	}
+	if name == "" {
+		return "", "", fmt.Errorf("gee: param name is empty in %q", part)
+	}
+	return name, constraint, nil
+}
+
+// compileConstraint 编译参数约束，内置约束直接使用预定义的正则表达式
+func compileConstraint(constraint string) (*regexp.Regexp, error) {
+	if expr, ok := paramConstraints[constraint]; ok {
+		constraint = expr
+	}
+	re, err := regexp.Compile("^(?:" + constraint + ")$") // 约束需要匹配整个片段
+	if err != nil {
+		return nil, fmt.Errorf("gee: invalid constraint %q: %v", constraint, err)
+	}
+	return re, nil
+}
+
+// newNode 创建保存片段 part 的节点，通配符片段的语法在这里校验，不合法时 panic
+func newNode(part string) *node {
+	n := &node{
+		part:     part,
+		children: make(map[string]*node),
+		isWild:   len(part) > 0 && (part[0] == ':' || part[0] == '*'),
+	}
+	if !n.isWild {
+		return n
+	}
+
+	name, constraint, err := parseParam(part)
+	if err != nil {
+		panic(err)
+	}
+	n.param = name
+	if constraint != "" {
+		if n.constraint, err = compileConstraint(constraint); err != nil {
+			panic(err)
+		}
+	}
+	return n
+}
+
+// priority 通配符节点的匹配优先级，数值越小越先匹配
+func (n *node) priority() int {
+	switch {
+	case n.part[0] == ':' && n.constraint != nil:
+		return 0
+	case n.part[0] == ':':
+		return 1
+	default:
+		return 2
+	}
+}
+
 func (root *node) insert(pattern string) {
 	cur := root
 	patterns := parsePath(pattern) // 提前将路由地址，分割成片段保存在数组中
@@ -33,10 +120,13 @@ func (root *node) insert(pattern string) {
 	// 依次遍历路由地址的片段，不存在则创建保存该片段的节点。
 	for _, part := range patterns {
 		if _, ok := cur.children[part]; !ok {
-			cur.children[part] = &node{
-				part:     part,
-				children: make(map[string]*node),
-				isWild:   part[0] == ':' || part[0] == '*',
+			child := newNode(part)
+			cur.children[part] = child
+			if child.isWild {
+				cur.wilds = append(cur.wilds, child)
+				sort.SliceStable(cur.wilds, func(i, j int) bool {
+					return cur.wilds[i].priority() < cur.wilds[j].priority()
+				})
 			}
 		}
 		cur = cur.children[part] // cur 指向保存当前片段的节点，后面的片段保存在 cur 当前节点的子节点中
@@ -47,33 +137,46 @@ func (root *node) insert(pattern string) {
 
 func (root *node) search(pattern string) (*node, map[string]string) {
 	params := make(map[string]string)
+	if n := root.match(parsePath(pattern), params); n != nil {
+		return n, params
+	}
+	return nil, nil
+}
 
-	cur := root
-	patterns := parsePath(pattern)
+// match 递归匹配剩余的片段 parts。匹配顺序为：精确匹配、带约束的 : 参数、不带约束的 : 参数、* 参数，
+// 某个分支后续无法匹配时回退，继续尝试下一个分支，因此 /user/abc 不满足 /user/:id<int> 时，仍然可以匹配 /user/:name
+func (cur *node) match(parts []string, params map[string]string) *node {
+	// 所有请求路经片段均匹配完毕，检查当前节点是否有完整的路由地址。比如,路由注册了 /a/b，请求路经是 /a，虽然也匹配上了，但 a 这个节点未保存完整的路经，只有最后的节点 b 节点会保存。
+	if len(parts) == 0 {
+		if cur.path != "" {
+			return cur
+		}
+		return nil
+	}
 
-	// 依次遍历路由地址的片段，无法匹配上则退出
-	for _, part := range patterns {
-		if cur.children[part] == nil { // 无法匹配上，开始尝试通配符匹配
-			for k, v := range cur.children { // 遍历当前片段下的所有子节点，排查有通配符的节点。
-				if v.isWild == true && k[0] == '*' { // 找到*，保存该片段及该片段后的所有内容做参数
-					params[k[1:]] = pattern[strings.Index(pattern, part):]
-					return v, params
-				} else if v.isWild == true && k[0] == ':' { // 找到：，保存该片段做参数
-					params[k[1:]] = part
-					cur = v
-					break
-				} else { // 没有通配符节点
-					return nil, nil
-				}
-			}
-		} else { // 当前片段准确匹配上，继续匹配后面的片段
-			cur = cur.children[part]
+	part := parts[0]
+	if child, ok := cur.children[part]; ok && !child.isWild { // 当前片段准确匹配上，继续匹配后面的片段
+		if n := child.match(parts[1:], params); n != nil {
+			return n
 		}
 	}
 
-	// 所有请求路经片段均匹配完毕，检查当前节点是否有完整的路由地址。比如,路由注册了 /a/b，请求路经是 /a，虽然也匹配上了，但 a 这个节点未保存完整的路经，只有最后的节点 b 节点会保存。
-	if cur.path != "" {
-		return cur, params
+	for _, child := range cur.wilds { // 无法准确匹配，开始尝试通配符匹配
+		if child.part[0] == '*' { // 找到*，保存该片段及该片段后的所有内容做参数
+			if child.path == "" {
+				continue
+			}
+			params[child.param] = strings.Join(parts, "/")
+			return child
+		}
+
+		if child.constraint != nil && !child.constraint.MatchString(part) { // 不满足约束，尝试下一个通配符节点
+			continue
+		}
+		if n := child.match(parts[1:], params); n != nil { // 找到：，保存该片段做参数
+			params[child.param] = part
+			return n
+		}
 	}
-	return nil, nil
+	return nil
 }
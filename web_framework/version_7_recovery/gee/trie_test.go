@@ -0,0 +1,93 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	root := &node{children: make(map[string]*node)}
+	for _, pattern := range []string{
+		"/user/:id<int>",
+		"/user/:name",
+		"/user/:id<int>/profile",
+		"/file/:name<[a-z]+\\.txt>",
+		"/obj/:uuid<uuid>",
+		"/assets/*filepath",
+		"/p/:lang/doc",
+		"/p/go/src",
+	} {
+		root.insert(pattern)
+	}
+
+	cases := []struct {
+		path, pattern string
+		params        map[string]string
+	}{
+		{"/user/42", "/user/:id<int>", map[string]string{"id": "42"}},
+		{"/user/tom", "/user/:name", map[string]string{"name": "tom"}},
+		{"/user/42/profile", "/user/:id<int>/profile", map[string]string{"id": "42"}},
+		{"/file/readme.txt", "/file/:name<[a-z]+\\.txt>", map[string]string{"name": "readme.txt"}},
+		{"/file/README.md", "", nil},
+		{"/obj/123e4567-e89b-12d3-a456-426614174000", "/obj/:uuid<uuid>", map[string]string{"uuid": "123e4567-e89b-12d3-a456-426614174000"}},
+		{"/obj/123", "", nil},
+		{"/assets/css/index.css", "/assets/*filepath", map[string]string{"filepath": "css/index.css"}},
+		{"/p/go/doc", "/p/:lang/doc", map[string]string{"lang": "go"}}, // 精确分支失败后回退到参数分支
+		{"/p/go/src", "/p/go/src", map[string]string{}},
+	}
+	for _, c := range cases {
+		n, params := root.search(c.path)
+		if c.pattern == "" {
+			if n != nil {
+				t.Fatalf("%s: expect no match, got %s", c.path, n.path)
+			}
+			continue
+		}
+		if n == nil || n.path != c.pattern {
+			t.Fatalf("%s: expect %s, got %v", c.path, c.pattern, n)
+		}
+		if len(params) != len(c.params) {
+			t.Fatalf("%s: expect params %v, got %v", c.path, c.params, params)
+		}
+		for k, v := range c.params {
+			if params[k] != v {
+				t.Fatalf("%s: expect params %v, got %v", c.path, c.params, params)
+			}
+		}
+	}
+}
+
+func TestInvalidConstraint(t *testing.T) {
+	for _, pattern := range []string{"/user/:id<int", "/user/:<int>", "/user/:id<>", "/user/:id<[a-z>", "/assets/*path<int>"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expect panic", pattern)
+				}
+			}()
+			New().GET(pattern, func(c *Context) {})
+		}()
+	}
+}
+
+func TestParamInt(t *testing.T) {
+	r := New()
+	r.GET("/user/:id<int>", func(c *Context) {
+		id, err := c.ParamInt("id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.String(http.StatusOK, "%d", id+1)
+	}, Name("user.show"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/41", nil))
+	if w.Body.String() != "42" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+
+	if _, err := r.URLFor("user.show", map[string]string{"id": "abc"}); err == nil {
+		t.Fatal("expect constraint error")
+	}
+}
@@ -0,0 +1,225 @@
+package gee
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiVersion 一个 API 版本及其弃用信息，同名的版本分组共享同一个 apiVersion
+type apiVersion struct {
+	name       string
+	deprecated bool
+	sunset     time.Time
+}
+
+// VersionOption 创建版本分组时的可选配置
+type VersionOption func(v *apiVersion)
+
+// Deprecated 标记版本已弃用，该版本的响应会带上 Deprecation: true 响应头
+func Deprecated() VersionOption {
+	return func(v *apiVersion) {
+		v.deprecated = true
+	}
+}
+
+// Sunset 设置版本的下线时间，该版本的响应会带上 Sunset 响应头，同时标记为已弃用
+func Sunset(t time.Time) VersionOption {
+	return func(v *apiVersion) {
+		v.deprecated = true
+		v.sunset = t
+	}
+}
+
+// VersioningOptions 版本协商的配置，通过 engine.Versioning 设置
+type VersioningOptions struct {
+	Header string // 指定版本的请求头，默认为 Api-Version，同时用于在响应中返回实际使用的版本
+	Vendor string // Accept 中 application/vnd.<Vendor>.v2+json 的厂商名，为空时接受任意厂商
+	// Default 请求未指定版本时使用的版本。为空或路由没有该版本时，使用未分版本的路由，没有则使用最新的版本
+	Default string
+}
+
+// Versioning 设置版本协商的配置
+func (engine *Engine) Versioning(opts VersioningOptions) {
+	if opts.Header == "" {
+		opts.Header = "Api-Version"
+	}
+	opts.Default = normalizeVersion(opts.Default)
+	engine.versioning = opts
+}
+
+// Version 创建一个版本分组，与当前分组共用路径前缀。同一路由地址可以在不同的版本分组中注册不同的处理函数，
+// 请求按 Api-Version 请求头或 Accept 中的 application/vnd.acme.v2+json、application/json; version=2 选择版本，
+// 请求的版本不存在时返回 406。版本分组的中间件只对选中该版本的请求生效
+func (group *RouterGroup) Version(version string, opts ...VersionOption) *RouterGroup {
+	version = normalizeVersion(version)
+	if version == "" {
+		panic("gee: empty API version")
+	}
+
+	engine := group.engine
+	v, ok := engine.versions[version]
+	if !ok {
+		v = &apiVersion{name: version}
+		if engine.versions == nil {
+			engine.versions = make(map[string]*apiVersion)
+		}
+		engine.versions[version] = v
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	newGroup := group.Group("")
+	newGroup.version = v
+	return newGroup
+}
+
+// Version 返回当前请求协商出的 API 版本，路由没有分版本时为空
+func (c *Context) Version() string {
+	return c.version
+}
+
+// normalizeVersion 去掉版本号前的 v，"v2" 与 "2" 视为同一个版本
+func normalizeVersion(version string) string {
+	version = strings.TrimSpace(version)
+	if len(version) > 1 && (version[0] == 'v' || version[0] == 'V') {
+		version = version[1:]
+	}
+	return version
+}
+
+// negotiateVersion 为匹配上的路由选择版本，返回选中的版本和处理函数，
+// 请求的版本不存在时返回 406 处理函数。unversioned 为同一路由未分版本的处理函数，可能为空
+func (engine *Engine) negotiateVersion(c *Context, versions map[string]HandlerFunc, unversioned HandlerFunc) (string, HandlerFunc) {
+	opts := engine.versioning
+	if opts.Header == "" {
+		opts.Header = "Api-Version"
+	}
+	c.Writer.Header().Add("Vary", opts.Header+", Accept")
+
+	if requested, ok := requestedVersion(c.Req, opts); ok {
+		if handler, ok := versions[requested]; ok {
+			engine.setVersionHeaders(c, opts, requested)
+			return requested, handler
+		}
+		return "", func(c *Context) {
+			c.Fail(http.StatusNotAcceptable, fmt.Sprintf("unsupported API version %q, supported versions: %s",
+				requested, strings.Join(sortedVersions(versions), ", ")))
+		}
+	}
+
+	if handler, ok := versions[opts.Default]; ok && opts.Default != "" {
+		engine.setVersionHeaders(c, opts, opts.Default)
+		return opts.Default, handler
+	}
+	if unversioned != nil {
+		return "", unversioned
+	}
+	all := sortedVersions(versions)
+	latest := all[len(all)-1]
+	engine.setVersionHeaders(c, opts, latest)
+	return latest, versions[latest]
+}
+
+func (engine *Engine) setVersionHeaders(c *Context, opts VersioningOptions, version string) {
+	header := c.Writer.Header()
+	header.Set(opts.Header, version)
+	v := engine.versions[version]
+	if v == nil || !v.deprecated {
+		return
+	}
+	header.Set("Deprecation", "true")
+	if !v.sunset.IsZero() {
+		header.Set("Sunset", v.sunset.UTC().Format(http.TimeFormat))
+	}
+}
+
+// requestedVersion 读取请求指定的版本，请求头优先于 Accept
+func requestedVersion(req *http.Request, opts VersioningOptions) (string, bool) {
+	if version := normalizeVersion(req.Header.Get(opts.Header)); version != "" {
+		return version, true
+	}
+	vendor := strings.ToLower(opts.Vendor)
+	for _, accept := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		if version := normalizeVersion(params["version"]); version != "" {
+			return version, true
+		}
+		// application/vnd.acme.v2+json
+		if !strings.HasPrefix(mediaType, "application/vnd.") {
+			continue
+		}
+		name := strings.TrimPrefix(mediaType, "application/vnd.")
+		if i := strings.IndexByte(name, '+'); i >= 0 {
+			name = name[:i]
+		}
+		i := strings.LastIndex(name, ".v")
+		if i <= 0 || i+2 == len(name) || name[i+2] < '0' || name[i+2] > '9' {
+			continue
+		}
+		if vendor != "" && name[:i] != vendor {
+			continue
+		}
+		return name[i+2:], true
+	}
+	return "", false
+}
+
+// sortedVersions 按版本号从小到大排序，例如 1 < 2 < 2.1 < 10
+func sortedVersions(versions map[string]HandlerFunc) []string {
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return compareVersions(names[i], names[j]) < 0
+	})
+	return names
+}
+
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, errX := strconv.Atoi(as[i])
+		y, errY := strconv.Atoi(bs[i])
+		if errX != nil || errY != nil { // 不是数字时按字符串比较
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+			continue
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(as) - len(bs)
+}
+
+// groupMiddlewares 返回路由树 r 中对 path 生效的分组中间件，
+// version 为空时只包含未分版本的分组，否则只包含该版本的分组
+func (engine *Engine) groupMiddlewares(r *router, path string, version string) []HandlerFunc {
+	var middlewares []HandlerFunc
+	for _, group := range engine.groups {
+		if group.router != r { // 只有同一棵路由树下的分组中间件才生效
+			continue
+		}
+		if (version == "" && group.version != nil) || (version != "" && (group.version == nil || group.version.name != version)) {
+			continue
+		}
+		if strings.HasPrefix(path, group.prefix+"/") {
+			middlewares = append(middlewares, group.middlewares...)
+		}
+	}
+	return middlewares
+}
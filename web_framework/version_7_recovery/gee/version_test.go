@@ -0,0 +1,114 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newVersionEngine() *Engine {
+	r := New()
+	r.Versioning(VersioningOptions{Vendor: "acme", Default: "1"})
+	api := r.Group("/api")
+	api.Use(func(c *Context) { c.SetHeader("X-API", "1") })
+
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	v1 := api.Version("1", Sunset(sunset))
+	v1.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "v1 user %s", c.Param("id"))
+	})
+	v2 := api.Version("v2")
+	v2.Use(func(c *Context) { c.SetHeader("X-V2", "1") })
+	v2.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "v%s user %s", c.Version(), c.Param("id"))
+	})
+	v2.GET("/orders", func(c *Context) {
+		c.String(http.StatusOK, "v2 orders")
+	})
+	api.GET("/orders", func(c *Context) {
+		c.String(http.StatusOK, "orders")
+	})
+	return r
+}
+
+func TestVersion(t *testing.T) {
+	r := newVersionEngine()
+	cases := []struct {
+		path, header, accept string
+		code                 int
+		body, version        string
+	}{
+		{"/api/users/1", "", "", http.StatusOK, "v1 user 1", "1"}, // 默认版本
+		{"/api/users/1", "2", "", http.StatusOK, "v2 user 1", "2"},
+		{"/api/users/1", "v2", "application/vnd.acme.v1+json", http.StatusOK, "v2 user 1", "2"}, // 请求头优先
+		{"/api/users/1", "", "text/html, application/vnd.acme.v2+json", http.StatusOK, "v2 user 1", "2"},
+		{"/api/users/1", "", "application/json; version=2", http.StatusOK, "v2 user 1", "2"},
+		{"/api/users/1", "", "application/vnd.other.v2+json", http.StatusOK, "v1 user 1", "1"}, // 其他厂商的类型被忽略
+		{"/api/users/1", "3", "", http.StatusNotAcceptable, `{"message":"unsupported API version \"3\", supported versions: 1, 2"}`, ""},
+		{"/api/orders", "", "", http.StatusOK, "orders", ""}, // 路由没有默认版本时使用未分版本的路由
+		{"/api/orders", "2", "", http.StatusOK, "v2 orders", "2"},
+		{"/api/orders", "1", "", http.StatusNotAcceptable, "", ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set("Api-Version", tc.header)
+		}
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code || (tc.body != "" && strings.TrimSpace(w.Body.String()) != tc.body) {
+			t.Fatalf("%s %q %q: expected %d %s, got %d %s", tc.path, tc.header, tc.accept, tc.code, tc.body, w.Code, w.Body.String())
+		}
+		if tc.version != "" && w.Header().Get("Api-Version") != tc.version {
+			t.Fatalf("%s %q %q: expected Api-Version %s, got %q", tc.path, tc.header, tc.accept, tc.version, w.Header().Get("Api-Version"))
+		}
+		if w.Header().Get("X-API") != "1" {
+			t.Fatalf("%s: parent group middleware should run for every version", tc.path)
+		}
+		if (w.Header().Get("X-V2") != "") != (tc.version == "2") {
+			t.Fatalf("%s %q: version middleware should only run for v2", tc.path, tc.header)
+		}
+	}
+}
+
+func TestVersionDeprecation(t *testing.T) {
+	r := newVersionEngine()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/users/1", nil))
+	if w.Header().Get("Deprecation") != "true" || w.Header().Get("Sunset") != "Tue, 01 Jan 2030 00:00:00 GMT" {
+		t.Fatalf("unexpected deprecation headers %v", w.Header())
+	}
+
+	req := httptest.NewRequest("GET", "/api/users/1", nil)
+	req.Header.Set("Api-Version", "2")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Deprecation") != "" || w.Header().Get("Vary") != "Api-Version, Accept" {
+		t.Fatalf("unexpected headers for v2 %v", w.Header())
+	}
+}
+
+func TestVersionRoutes(t *testing.T) {
+	r := newVersionEngine()
+	versions := map[string]int{}
+	for _, route := range r.Routes() {
+		if route.Pattern == "/api/users/:id" {
+			versions[route.Version] = route.Middlewares
+		}
+	}
+	if len(versions) != 2 || versions["1"] != 1 || versions["2"] != 2 {
+		t.Fatalf("unexpected versioned routes %v", versions)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	versions := map[string]HandlerFunc{"10": nil, "2": nil, "2.1": nil, "1": nil}
+	if got := sortedVersions(versions); got[0] != "1" || got[1] != "2" || got[2] != "2.1" || got[3] != "10" {
+		t.Fatalf("unexpected order %v", got)
+	}
+}
@@ -0,0 +1,55 @@
+package gee
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WrapH 将 http.Handler 包装为 HandlerFunc，例如 r.GET("/metrics", gee.WrapH(promhttp.Handler()))
+func WrapH(h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		h.ServeHTTP(c.Writer, c.Req)
+	}
+}
+
+// WrapF 将 http.HandlerFunc 包装为 HandlerFunc
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		f(c.Writer, c.Req)
+	}
+}
+
+// Mount 将 http.Handler 挂载到 prefix 下，所有请求方法的 prefix 及其子路径都交给 h 处理，
+// h 看到的请求路径会去掉分组前缀和 prefix，例如挂载在 "/admin" 下，请求 "/admin/users" 时 h 看到的是 "/users"。
+// h 也可以是另一个 *Engine，从而实现子应用。
+func (group *RouterGroup) Mount(prefix string, h http.Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	absolutePath := strings.TrimSuffix(group.prefix+prefix, "/") // 需要从请求路径中去掉的完整前缀
+	handler := func(c *Context) {
+		h.ServeHTTP(c.Writer, stripPrefix(c.Req, absolutePath))
+	}
+
+	group.Any(prefix+"/", handler)           // 挂载点本身，例如 "/admin"
+	group.Any(prefix+"/*mountpath", handler) // 挂载点下的子路径，例如 "/admin/users"
+}
+
+// stripPrefix 复制请求，并从路径中去掉 prefix，与 http.StripPrefix 不同的是，去掉前缀后的路径至少为 "/"
+func stripPrefix(req *http.Request, prefix string) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.URL = new(url.URL)
+	*r.URL = *req.URL
+
+	r.URL.Path = strings.TrimPrefix(req.URL.Path, prefix)
+	if !strings.HasPrefix(r.URL.Path, "/") {
+		r.URL.Path = "/" + r.URL.Path
+	}
+	if req.URL.RawPath != "" {
+		r.URL.RawPath = strings.TrimPrefix(req.URL.RawPath, prefix)
+		if !strings.HasPrefix(r.URL.RawPath, "/") {
+			r.URL.RawPath = "/" + r.URL.RawPath
+		}
+	}
+	return r
+}
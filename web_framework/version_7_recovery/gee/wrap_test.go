@@ -0,0 +1,82 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	sub := New()
+	sub.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "sub index")
+	})
+	sub.POST("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "sub user %s", c.Param("id"))
+	})
+
+	r := New()
+	api := r.Group("/api")
+	api.Use(func(c *Context) {
+		c.SetHeader("X-Api", "1")
+	})
+	api.Mount("/sub", sub)
+	api.Mount("/raw/", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("raw " + req.URL.Path))
+	}))
+
+	cases := []struct {
+		method, path, body string
+	}{
+		{"GET", "/api/sub", "sub index"},
+		{"GET", "/api/sub/", "sub index"},
+		{"POST", "/api/sub/users/7", "sub user 7"},
+		{"DELETE", "/api/raw/a/b", "raw /a/b"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+		if w.Body.String() != c.body {
+			t.Fatalf("%s %s: got %q, want %q", c.method, c.path, w.Body.String(), c.body)
+		}
+		if w.Header().Get("X-Api") != "1" {
+			t.Fatalf("%s %s: group middleware not applied", c.method, c.path)
+		}
+	}
+}
+
+func TestEnableDebugRoutes(t *testing.T) {
+	r := New()
+	debug := r.Group("/debug")
+	debug.Use(func(c *Context) {
+		if c.Query("token") != "secret" {
+			c.Fail(http.StatusForbidden, "forbidden")
+		}
+	})
+	r.EnableDebugRoutes(debug)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/vars", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expect 403, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/vars?token=secret", nil))
+	if !strings.Contains(w.Body.String(), "memstats") {
+		t.Fatalf("expect expvar output, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/pprof/?token=secret", nil))
+	if !strings.Contains(w.Body.String(), "goroutine") {
+		t.Fatalf("expect pprof index, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/pprof/goroutine?debug=1&token=secret", nil))
+	if !strings.Contains(w.Body.String(), "goroutine profile") {
+		t.Fatalf("expect goroutine profile, got %q", w.Body.String())
+	}
+}